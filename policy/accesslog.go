@@ -0,0 +1,162 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/trinet2005/oss-pkg/policy/condition"
+)
+
+// DefaultSiblingThreshold - default number of sibling keys generalized to
+// a prefix wildcard by FromAccessLog.
+const DefaultSiblingThreshold = 3
+
+// AccessEntry - observed access of an action to a resource, e.g.
+// "mybucket" or "mybucket/photos/a.jpg", optionally with ARNPrefix.
+type AccessEntry struct {
+	Action   Action `json:"action"`
+	Resource string `json:"resource"`
+}
+
+// AccessLogOptions - options of FromAccessLogWithOptions.
+type AccessLogOptions struct {
+	// SiblingThreshold - number of distinct keys of one action directly
+	// under a prefix at which the keys are generalized to "prefix/*". Zero
+	// or less keeps every key as observed.
+	SiblingThreshold int
+}
+
+// FromAccessLog - synthesizes a minimal Allow policy covering given accesses
+// with DefaultSiblingThreshold, see FromAccessLogWithOptions.
+func FromAccessLog(entries []AccessEntry) Policy {
+	return FromAccessLogWithOptions(entries, AccessLogOptions{SiblingThreshold: DefaultSiblingThreshold})
+}
+
+// FromAccessLogWithOptions - synthesizes a minimal Allow policy covering
+// given accesses. Object keys accessed by an action are grouped by their
+// parent prefix, i.e. up to the last '/'; once a prefix has at least
+// SiblingThreshold distinct keys, they are replaced by "prefix/*", which
+// also covers deeper keys, e.g. "mybucket/photos/a.jpg", "mybucket/photos/b.jpg"
+// and "mybucket/photos/c.jpg" become "mybucket/photos/*". Only the direct
+// parent is generalized, never a higher prefix. Resources sharing the same
+// actions form one statement. As resource patterns have no escaping, keys
+// containing '*' or '?' match more than observed.
+func FromAccessLogWithOptions(entries []AccessEntry, opts AccessLogOptions) Policy {
+	resources := map[Action]map[string]struct{}{}
+	for _, entry := range entries {
+		resource := strings.TrimPrefix(entry.Resource, ARNPrefix())
+		if entry.Action == "" || resource == "" {
+			continue
+		}
+
+		if _, ok := resources[entry.Action]; !ok {
+			resources[entry.Action] = map[string]struct{}{}
+		}
+		resources[entry.Action][resource] = struct{}{}
+	}
+
+	resourceActions := map[string][]Action{}
+	for action, actionResources := range resources {
+		for _, resource := range generalizeKeys(actionResources, opts.SiblingThreshold) {
+			resourceActions[resource] = append(resourceActions[resource], action)
+		}
+	}
+
+	groups := map[string][]string{}
+	groupActions := map[string][]Action{}
+	for resource, actions := range resourceActions {
+		sort.Slice(actions, func(i, j int) bool { return actions[i] < actions[j] })
+		names := make([]string, len(actions))
+		for i, action := range actions {
+			names[i] = string(action)
+		}
+		key := strings.Join(names, ",")
+		groups[key] = append(groups[key], resource)
+		groupActions[key] = actions
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	policy := Policy{Version: DefaultVersion}
+	for _, key := range keys {
+		resourceSet := NewResourceSet()
+		for _, resource := range groups[key] {
+			resourceSet.Add(NewResource(resource))
+		}
+		policy.Statements = append(policy.Statements, NewStatement("",
+			Allow,
+			NewActionSet(groupActions[key]...),
+			resourceSet,
+			condition.NewFunctions(),
+		))
+	}
+
+	return policy
+}
+
+// generalizeKeys - returns resources with keys of a parent prefix replaced by
+// "prefix/*" once there are at least threshold of them, dropping resources
+// covered by a generalized prefix.
+func generalizeKeys(resources map[string]struct{}, threshold int) []string {
+	var buckets []string
+	siblings := map[string][]string{}
+	for resource := range resources {
+		i := strings.LastIndex(resource, "/")
+		if i < 0 {
+			buckets = append(buckets, resource)
+			continue
+		}
+		parent := resource[:i+1]
+		siblings[parent] = append(siblings[parent], resource)
+	}
+
+	var prefixes []string
+	for parent, keys := range siblings {
+		if threshold > 0 && len(keys) >= threshold {
+			prefixes = append(prefixes, parent)
+		}
+	}
+
+	covered := func(resource string) bool {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(resource, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+
+	result := buckets
+	for _, prefix := range prefixes {
+		if !covered(strings.TrimSuffix(prefix, "/")) {
+			result = append(result, prefix+"*")
+		}
+	}
+	for parent, keys := range siblings {
+		if !covered(parent) {
+			result = append(result, keys...)
+		}
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/trinet2005/oss-pkg/policy/condition"
+)
+
+func TestFromAccessLog(t *testing.T) {
+	photos := []AccessEntry{
+		{GetObjectAction, "mybucket/photos/a.jpg"},
+		{GetObjectAction, "mybucket/photos/b.jpg"},
+		{GetObjectAction, "arn:aws:s3:::mybucket/photos/c.jpg"},
+		{GetObjectAction, "mybucket/photos/c.jpg"},
+	}
+
+	testCases := []struct {
+		entries            []AccessEntry
+		threshold          int
+		expectedStatements []Statement
+	}{
+		// Repeated keys under a prefix collapse into prefix wildcard.
+		{photos, 3, []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction),
+				NewResourceSet(NewResource("mybucket/photos/*")), condition.NewFunctions()),
+		}},
+		// Below threshold keys are kept as observed.
+		{photos, 4, []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction),
+				NewResourceSet(
+					NewResource("mybucket/photos/a.jpg"),
+					NewResource("mybucket/photos/b.jpg"),
+					NewResource("mybucket/photos/c.jpg"),
+				), condition.NewFunctions()),
+		}},
+		// Generalization disabled.
+		{photos, 0, []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction),
+				NewResourceSet(
+					NewResource("mybucket/photos/a.jpg"),
+					NewResource("mybucket/photos/b.jpg"),
+					NewResource("mybucket/photos/c.jpg"),
+				), condition.NewFunctions()),
+		}},
+		// Deeper keys are covered by the generalized prefix, sibling
+		// prefixes and bucket accesses are kept.
+		{append([]AccessEntry{
+			{GetObjectAction, "mybucket/photos/2024/d.jpg"},
+			{GetObjectAction, "mybucket/docs/a.txt"},
+			{ListBucketAction, "mybucket"},
+		}, photos...), 3, []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction),
+				NewResourceSet(
+					NewResource("mybucket/photos/*"),
+					NewResource("mybucket/docs/a.txt"),
+				), condition.NewFunctions()),
+			NewStatement("", Allow, NewActionSet(ListBucketAction),
+				NewResourceSet(NewResource("mybucket")), condition.NewFunctions()),
+		}},
+		// Resources with the same actions share a statement, keys are
+		// generalized per action.
+		{[]AccessEntry{
+			{GetObjectAction, "mybucket/a"},
+			{PutObjectAction, "mybucket/a"},
+			{GetObjectAction, "mybucket/b"},
+			{PutObjectAction, "mybucket/b"},
+			{GetObjectAction, "mybucket/c"},
+		}, 3, []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction),
+				NewResourceSet(NewResource("mybucket/*")), condition.NewFunctions()),
+			NewStatement("", Allow, NewActionSet(PutObjectAction),
+				NewResourceSet(NewResource("mybucket/a"), NewResource("mybucket/b")), condition.NewFunctions()),
+		}},
+		{nil, 3, nil},
+	}
+
+	for i, testCase := range testCases {
+		policy := FromAccessLogWithOptions(testCase.entries, AccessLogOptions{SiblingThreshold: testCase.threshold})
+		if policy.Version != DefaultVersion {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, DefaultVersion, policy.Version)
+		}
+		if len(policy.Statements) != len(testCase.expectedStatements) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedStatements, policy.Statements)
+		}
+		for j, statement := range policy.Statements {
+			if !statement.Equals(testCase.expectedStatements[j]) {
+				t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedStatements[j], statement)
+			}
+		}
+		if err := policy.Validate(); err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		for _, entry := range testCase.entries {
+			tokens := strings.SplitN(strings.TrimPrefix(entry.Resource, ARNPrefix()), "/", 2)
+			args := Args{Action: entry.Action, BucketName: tokens[0], ConditionValues: map[string][]string{}}
+			if len(tokens) == 2 {
+				args.ObjectName = tokens[1]
+			}
+			if !policy.IsAllowed(args) {
+				t.Fatalf("case %v: expected %v to be allowed", i+1, entry)
+			}
+		}
+	}
+}
+
+func TestFromAccessLogDefaultThreshold(t *testing.T) {
+	policy := FromAccessLog([]AccessEntry{
+		{GetObjectAction, "mybucket/photos/a.jpg"},
+		{GetObjectAction, "mybucket/photos/b.jpg"},
+		{GetObjectAction, "mybucket/photos/c.jpg"},
+	})
+	expected := NewStatement("", Allow, NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/photos/*")), condition.NewFunctions())
+	if len(policy.Statements) != 1 || !policy.Statements[0].Equals(expected) {
+		t.Fatalf("expected: %v, got: %v", expected, policy.Statements)
+	}
+}
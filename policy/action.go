@@ -18,6 +18,8 @@
 package policy
 
 import (
+	"sort"
+
 	"github.com/trinet2005/oss-pkg/policy/condition"
 	"github.com/trinet2005/oss-pkg/wildcard"
 )
@@ -284,6 +286,87 @@ var supportedObjectActions = map[Action]struct{}{
 	PutObjectFanOutAction:                {},
 }
 
+// actionResourceKinds - kind of resource each supported action, other than
+// AllActions, applies to. Object kinds must agree with supportedObjectActions
+// and every supported action must be listed when adding actions.
+var actionResourceKinds = map[Action]ResourceKind{
+	AbortMultipartUploadAction:             ObjectResourceKind,
+	CreateBucketAction:                     BucketResourceKind,
+	DeleteBucketAction:                     BucketResourceKind,
+	ForceDeleteBucketAction:                BucketResourceKind,
+	DeleteBucketPolicyAction:               BucketResourceKind,
+	DeleteObjectAction:                     ObjectResourceKind,
+	GetBucketLocationAction:                BucketResourceKind,
+	GetBucketNotificationAction:            BucketResourceKind,
+	GetBucketPolicyAction:                  BucketResourceKind,
+	GetObjectAction:                        ObjectResourceKind,
+	HeadBucketAction:                       BucketResourceKind,
+	ListAllMyBucketsAction:                 BucketResourceKind,
+	ListBucketAction:                       BucketResourceKind,
+	GetBucketPolicyStatusAction:            BucketResourceKind,
+	ListBucketVersionsAction:               BucketResourceKind,
+	ListBucketMultipartUploadsAction:       BucketResourceKind,
+	ListenNotificationAction:               BucketResourceKind,
+	ListenBucketNotificationAction:         BucketResourceKind,
+	ListMultipartUploadPartsAction:         ObjectResourceKind,
+	PutBucketLifecycleAction:               BucketResourceKind,
+	GetBucketLifecycleAction:               BucketResourceKind,
+	PutBucketNotificationAction:            BucketResourceKind,
+	PutBucketPolicyAction:                  BucketResourceKind,
+	PutObjectAction:                        ObjectResourceKind,
+	BypassGovernanceRetentionAction:        ObjectResourceKind,
+	PutObjectRetentionAction:               ObjectResourceKind,
+	GetObjectRetentionAction:               ObjectResourceKind,
+	GetObjectLegalHoldAction:               ObjectResourceKind,
+	PutObjectLegalHoldAction:               ObjectResourceKind,
+	GetBucketObjectLockConfigurationAction: BucketResourceKind,
+	PutBucketObjectLockConfigurationAction: BucketResourceKind,
+	GetBucketTaggingAction:                 BucketResourceKind,
+	PutBucketTaggingAction:                 BucketResourceKind,
+	GetObjectVersionAction:                 ObjectResourceKind,
+	GetObjectVersionTaggingAction:          ObjectResourceKind,
+	DeleteObjectVersionAction:              ObjectResourceKind,
+	DeleteObjectVersionTaggingAction:       ObjectResourceKind,
+	PutObjectVersionTaggingAction:          ObjectResourceKind,
+	GetObjectTaggingAction:                 ObjectResourceKind,
+	PutObjectTaggingAction:                 ObjectResourceKind,
+	DeleteObjectTaggingAction:              ObjectResourceKind,
+	PutBucketEncryptionAction:              BucketResourceKind,
+	GetBucketEncryptionAction:              BucketResourceKind,
+	PutBucketVersioningAction:              BucketResourceKind,
+	GetBucketVersioningAction:              BucketResourceKind,
+	GetReplicationConfigurationAction:      BucketResourceKind,
+	PutReplicationConfigurationAction:      BucketResourceKind,
+	ReplicateObjectAction:                  ObjectResourceKind,
+	ReplicateDeleteAction:                  ObjectResourceKind,
+	ReplicateTagsAction:                    ObjectResourceKind,
+	GetObjectVersionForReplicationAction:   ObjectResourceKind,
+	RestoreObjectAction:                    ObjectResourceKind,
+	ResetBucketReplicationStateAction:      ObjectResourceKind,
+	PutObjectFanOutAction:                  ObjectResourceKind,
+}
+
+// SuggestedActions - returns the sorted S3 actions applying to the kind of
+// given resource, i.e. bucket-level actions like "s3:ListBucket" for bucket
+// resources and object-level actions like "s3:GetObject" for object
+// resources. Nil is returned for other kinds of resources.
+func SuggestedActions(r Resource) []string {
+	kind := r.Kind()
+	if kind != BucketResourceKind && kind != ObjectResourceKind {
+		return nil
+	}
+
+	actions := []string{}
+	for action, actionKind := range actionResourceKinds {
+		if actionKind == kind {
+			actions = append(actions, string(action))
+		}
+	}
+	sort.Strings(actions)
+
+	return actions
+}
+
 // IsObjectAction - returns whether action is object type or not.
 func (action Action) IsObjectAction() bool {
 	for supAction := range supportedObjectActions {
@@ -18,6 +18,7 @@
 package policy
 
 import (
+	"sort"
 	"testing"
 )
 
@@ -61,3 +62,76 @@ func TestActionIsValid(t *testing.T) {
 		}
 	}
 }
+
+func TestSuggestedActions(t *testing.T) {
+	job, err := parseResource("arn:aws:s3:us-east-1:111122223333:job/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	contains := func(actions []string, action Action) bool {
+		for _, a := range actions {
+			if a == string(action) {
+				return true
+			}
+		}
+		return false
+	}
+
+	testCases := []struct {
+		resource        Resource
+		expectedActions []Action
+		excludedActions []Action
+	}{
+		{NewResource("mybucket"), []Action{ListBucketAction, GetBucketPolicyAction, CreateBucketAction}, []Action{GetObjectAction, PutObjectAction, AllActions}},
+		{NewResource("mybucket*"), []Action{ListBucketAction}, []Action{GetObjectAction}},
+		{NewResource("mybucket/*"), []Action{GetObjectAction, PutObjectAction, DeleteObjectAction}, []Action{ListBucketAction, GetBucketPolicyAction, AllActions}},
+		{NewResource("mybucket/photos/1.jpg"), []Action{GetObjectAction}, []Action{ListBucketAction}},
+	}
+
+	for i, testCase := range testCases {
+		result := SuggestedActions(testCase.resource)
+
+		if !sort.StringsAreSorted(result) {
+			t.Fatalf("case %v: expected sorted actions, got: %v", i+1, result)
+		}
+		for _, action := range testCase.expectedActions {
+			if !contains(result, action) {
+				t.Fatalf("case %v: expected: %v in %v", i+1, action, result)
+			}
+		}
+		for _, action := range testCase.excludedActions {
+			if contains(result, action) {
+				t.Fatalf("case %v: unexpected: %v in %v", i+1, action, result)
+			}
+		}
+	}
+
+	if result := SuggestedActions(job); result != nil {
+		t.Fatalf("expected: %v, got: %v", nil, result)
+	}
+	if result := SuggestedActions(NewResource("")); result != nil {
+		t.Fatalf("expected: %v, got: %v", nil, result)
+	}
+}
+
+func TestActionResourceKinds(t *testing.T) {
+	for action := range supportedActions {
+		if action == AllActions {
+			continue
+		}
+		kind, ok := actionResourceKinds[action]
+		if !ok {
+			t.Fatalf("%v: missing resource kind", action)
+		}
+		if _, isObject := supportedObjectActions[action]; isObject != (kind == ObjectResourceKind) {
+			t.Fatalf("%v: expected object action: %v, got: %v", action, isObject, kind)
+		}
+	}
+
+	for action := range actionResourceKinds {
+		if _, ok := supportedActions[action]; !ok {
+			t.Fatalf("%v: unsupported action", action)
+		}
+	}
+}
@@ -195,6 +195,17 @@ func (actionSet ActionSet) Validate() error {
 	return nil
 }
 
+// validateNoVariables - checks that no action uses a policy variable,
+// element is the policy element name used in the returned error.
+func (actionSet ActionSet) validateNoVariables(element string) error {
+	for action := range actionSet {
+		if hasPolicyVariable(string(action)) {
+			return Errorf("%w", VariableError{Element: element, Value: string(action)})
+		}
+	}
+	return nil
+}
+
 // NewActionSet - creates new action set.
 func NewActionSet(actions ...Action) ActionSet {
 	actionSet := make(ActionSet)
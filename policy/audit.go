@@ -0,0 +1,245 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// AuditCode - category of an audit finding.
+type AuditCode string
+
+const (
+	// AuditUnreachableStatement - Allow statement which never allows
+	// anything as an unconditional Deny statement covers all its actions
+	// and resources.
+	AuditUnreachableStatement AuditCode = "unreachable-statement"
+
+	// AuditRedundantResource - resource subsumed by another resource of the
+	// same statement, see Statement.RedundantResources.
+	AuditRedundantResource AuditCode = "redundant-resource"
+
+	// AuditBroadGrant - unconditional Allow of all actions of a service,
+	// e.g. "s3:*", of all actions but some by NotAction, or of all buckets,
+	// e.g. "arn:aws:s3:::*".
+	AuditBroadGrant AuditCode = "broad-grant"
+
+	// AuditConflict - Allow statement partially overridden by a Deny
+	// statement, i.e. some of its actions and resources are denied.
+	AuditConflict AuditCode = "allow-deny-conflict"
+)
+
+// AuditLocation - location of an audit finding in the policy.
+type AuditLocation struct {
+	// StatementIndex is the index of the statement in the policy.
+	StatementIndex int
+	// SID is the Sid of the statement, if any.
+	SID ID
+	// Element is the policy element of the finding, e.g. "Resource", empty
+	// when the finding is about the whole statement.
+	Element string
+	// Value is the offending value of Element, if any.
+	Value string
+}
+
+// AuditFinding - finding of Policy.Audit.
+type AuditFinding struct {
+	Code     AuditCode
+	Location AuditLocation
+	// Message is a human readable description of the finding.
+	Message string
+}
+
+// String - returns human readable form of the finding.
+func (f AuditFinding) String() string {
+	return fmt.Sprintf("%v: %v", f.Code, f.Message)
+}
+
+// AuditReport - findings of Policy.Audit, ordered by statement.
+type AuditReport struct {
+	Findings []AuditFinding
+}
+
+// IsEmpty - checks whether the report has no findings.
+func (report AuditReport) IsEmpty() bool {
+	return len(report.Findings) == 0
+}
+
+// ByCode - returns findings of given code.
+func (report AuditReport) ByCode(code AuditCode) []AuditFinding {
+	var findings []AuditFinding
+	for _, f := range report.Findings {
+		if f.Code == code {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Audit - analyzes the policy in one pass and reports, for each statement in
+// order, whether it is unreachable, its redundant resources, its broad
+// grants and its conflicts with Deny statements. Actions and resources are
+// compared by containment of their patterns, see wildcard.MatchPattern, so
+// overlapping patterns which do not contain each other, e.g. "s3:Get*" and
+// "s3:*Object", are neither reported as covering nor as conflicting.
+// Conditional Deny statements are not considered to make a statement
+// unreachable, only to conflict with it. A Deny statement using NotAction
+// denies all actions but those, it covers an Allow statement only if none
+// of its actions, all without wildcards, is excluded by the NotAction.
+func (iamp Policy) Audit() AuditReport {
+	var report AuditReport
+	add := func(code AuditCode, i int, element, value, format string, a ...interface{}) {
+		report.Findings = append(report.Findings, AuditFinding{
+			Code: code,
+			Location: AuditLocation{
+				StatementIndex: i,
+				SID:            iamp.Statements[i].SID,
+				Element:        element,
+				Value:          value,
+			},
+			Message: explainStatement(i, iamp.Statements[i]) + ": " + fmt.Sprintf(format, a...),
+		})
+	}
+
+	for i, statement := range iamp.Statements {
+		var conflicts []int
+		unreachable := -1
+		if statement.Effect == Allow {
+			for j, deny := range iamp.Statements {
+				if deny.Effect != Deny {
+					continue
+				}
+				if len(deny.Conditions) == 0 && coversStatement(deny, statement) {
+					unreachable = j
+					break
+				}
+				if overlapsStatement(deny, statement) {
+					conflicts = append(conflicts, j)
+				}
+			}
+		}
+
+		if unreachable >= 0 {
+			add(AuditUnreachableStatement, i, "", "", "never allows anything as %v denies all its actions and resources",
+				explainStatement(unreachable, iamp.Statements[unreachable]))
+		}
+
+		for _, resource := range statement.RedundantResources() {
+			add(AuditRedundantResource, i, "Resource", resource.String(), "resource '%v' is subsumed by another resource of the statement", resource)
+		}
+
+		if statement.Effect == Allow && len(statement.Conditions) == 0 {
+			actions := statement.Actions.ToSlice()
+			sort.Slice(actions, func(i, j int) bool { return actions[i] < actions[j] })
+			for _, action := range actions {
+				if strings.HasSuffix(string(action), ":*") || action == "*" {
+					add(AuditBroadGrant, i, "Action", string(action), "allows all '%v' actions unconditionally", action)
+				}
+			}
+			if len(statement.NotActions) != 0 {
+				add(AuditBroadGrant, i, "NotAction", statement.NotActions.String(), "allows all actions except %v unconditionally", statement.NotActions)
+			}
+			for _, resource := range statement.Resources.sortedResources() {
+				if !resource.isControlPlane() && strings.SplitN(resource.Pattern, "/", 2)[0] == "*" {
+					add(AuditBroadGrant, i, "Resource", resource.String(), "allows resource '%v' of all buckets unconditionally", resource)
+				}
+			}
+		}
+
+		if unreachable < 0 {
+			for _, j := range conflicts {
+				add(AuditConflict, i, "", "", "allows actions on resources which %v denies", explainStatement(j, iamp.Statements[j]))
+			}
+		}
+	}
+
+	return report
+}
+
+// ignoresResources - checks whether resources are not matched by the
+// statement, i.e. it has admin or KMS actions.
+func (statement Statement) ignoresResources() bool {
+	return statement.isAdmin() || statement.isKMS()
+}
+
+// coversStatement - checks whether every action and resource of statement
+// is contained in an action and resource of deny.
+func coversStatement(deny, statement Statement) bool {
+	if len(statement.Actions) == 0 || len(statement.NotActions) != 0 {
+		return false
+	}
+	for action := range statement.Actions {
+		if deny.NotActions.IsEmpty() {
+			if !containsAction(deny.Actions, action) {
+				return false
+			}
+			continue
+		}
+		// A pattern may have actions excluded by NotAction.
+		if strings.ContainsAny(string(action), "*?") || deny.NotActions.Match(action) {
+			return false
+		}
+	}
+
+	if deny.ignoresResources() {
+		return true
+	}
+	if statement.ignoresResources() || len(statement.Resources) == 0 {
+		return false
+	}
+	for resource := range statement.Resources {
+		if !containsResource(deny.Resources, resource) {
+			return false
+		}
+	}
+	return true
+}
+
+// overlapsStatement - checks whether deny and statement have an action and
+// a resource in common as per containment.
+func overlapsStatement(deny, statement Statement) bool {
+	switch {
+	case !deny.NotActions.IsEmpty():
+		// Every action not excluded by NotAction is denied, and a NotAction
+		// statement allows nearly all actions.
+		actions := len(statement.Actions) == 0
+		for action := range statement.Actions {
+			actions = actions || !containsAction(deny.NotActions, action)
+		}
+		if !actions {
+			return false
+		}
+	case len(statement.Actions) == 0:
+		actions := false
+		for action := range deny.Actions {
+			actions = actions || !statement.NotActions.Match(action)
+		}
+		if !actions {
+			return false
+		}
+	case len(intersectActions(deny.Actions, statement.Actions)) == 0:
+		return false
+	}
+
+	if deny.ignoresResources() || statement.ignoresResources() {
+		return true
+	}
+	return len(intersectResources(deny.Resources, statement.Resources)) != 0
+}
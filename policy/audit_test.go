@@ -0,0 +1,123 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicyAudit(t *testing.T) {
+	p, err := ParseConfig(strings.NewReader(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Sid": "ReadPhotos", "Effect": "Allow", "Action": "s3:GetObject", "Resource": ["arn:aws:s3:::mybucket/*", "arn:aws:s3:::mybucket/photos/*"]},
+        {"Sid": "Admin", "Effect": "Allow", "Action": "s3:*", "Resource": "arn:aws:s3:::*"},
+        {"Sid": "Archive", "Effect": "Allow", "Action": ["s3:PutObject", "s3:DeleteObject"], "Resource": "arn:aws:s3:::archive/old/*"},
+        {"Sid": "DenyArchive", "Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::archive/*"},
+        {"Sid": "DenySecrets", "Effect": "Deny", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/secrets/*"},
+        {"Sid": "Scoped", "Effect": "Allow", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::yourbucket",
+         "Condition": {"StringEquals": {"s3:prefix": "public/"}}},
+        {"Sid": "AllButDelete", "Effect": "Allow", "NotAction": "s3:DeleteObject", "Resource": "arn:aws:s3:::logs/*"}
+    ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	type finding struct {
+		code           AuditCode
+		statementIndex int
+		sid            ID
+		element        string
+		value          string
+	}
+	expectedResult := []finding{
+		{AuditRedundantResource, 0, "ReadPhotos", "Resource", "arn:aws:s3:::mybucket/photos/*"},
+		{AuditConflict, 0, "ReadPhotos", "", ""},
+		{AuditBroadGrant, 1, "Admin", "Action", "s3:*"},
+		{AuditBroadGrant, 1, "Admin", "Resource", "arn:aws:s3:::*"},
+		{AuditConflict, 1, "Admin", "", ""},
+		{AuditConflict, 1, "Admin", "", ""},
+		{AuditUnreachableStatement, 2, "Archive", "", ""},
+		{AuditBroadGrant, 6, "AllButDelete", "NotAction", "[s3:DeleteObject]"},
+	}
+
+	report := p.Audit()
+	var result []finding
+	for _, f := range report.Findings {
+		result = append(result, finding{f.Code, f.Location.StatementIndex, f.Location.SID, f.Location.Element, f.Location.Value})
+		if !strings.HasPrefix(f.Message, "statement ") {
+			t.Fatalf("unexpected message: %v", f.Message)
+		}
+	}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, result)
+	}
+
+	if findings := report.ByCode(AuditUnreachableStatement); len(findings) != 1 || !strings.Contains(findings[0].Message, "statement 4 (Sid 'DenyArchive')") {
+		t.Fatalf("unexpected findings: %v", findings)
+	}
+	if report.IsEmpty() {
+		t.Fatalf("expected findings")
+	}
+
+	// Deny statements using NotAction deny all but those actions.
+	p, err = ParseConfig(strings.NewReader(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"},
+        {"Effect": "Allow", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::mybucket/uploads/*"},
+        {"Effect": "Allow", "Action": "s3:Put*", "Resource": "arn:aws:s3:::mybucket/*"},
+        {"Effect": "Allow", "NotAction": "s3:DeleteObject", "Resource": "arn:aws:s3:::mybucket/logs/*",
+         "Condition": {"StringEquals": {"aws:username": "alice"}}},
+        {"Effect": "Deny", "NotAction": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"}
+    ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedResult = []finding{
+		{AuditUnreachableStatement, 1, "", "", ""},
+		{AuditConflict, 2, "", "", ""},
+		{AuditConflict, 3, "", "", ""},
+	}
+	result = nil
+	for _, f := range p.Audit().Findings {
+		result = append(result, finding{f.Code, f.Location.StatementIndex, f.Location.SID, f.Location.Element, f.Location.Value})
+	}
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, result)
+	}
+
+	// A tight policy has no findings.
+	p, err = ParseConfig(strings.NewReader(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/photos/*"},
+        {"Effect": "Deny", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/secrets/*"}
+    ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report := p.Audit(); !report.IsEmpty() {
+		t.Fatalf("expected no findings, got: %v", report.Findings)
+	}
+}
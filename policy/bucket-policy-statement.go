@@ -28,7 +28,7 @@ type BPStatement struct {
 	SID        ID                  `json:"Sid,omitempty"`
 	Effect     Effect              `json:"Effect"`
 	Principal  Principal           `json:"Principal"`
-	Actions    ActionSet           `json:"Action"`
+	Actions    ActionSet           `json:"Action,omitempty"`
 	NotActions ActionSet           `json:"NotAction,omitempty"`
 	Resources  ResourceSet         `json:"Resource"`
 	Conditions condition.Functions `json:"Condition,omitempty"`
@@ -36,8 +36,19 @@ type BPStatement struct {
 
 // IsAllowed - checks given policy args is allowed to continue the Rest API.
 func (statement BPStatement) IsAllowed(args BucketPolicyArgs) bool {
+	if args.Anonymous {
+		if statement.Effect == Allow && usesIdentityKeys(statement.Resources, statement.Conditions) {
+			return false
+		}
+		args.ConditionValues = anonymousArgs(Args{ConditionValues: args.ConditionValues}).ConditionValues
+	}
+
 	check := func() bool {
-		if !statement.Principal.Match(args.AccountName) {
+		if args.Anonymous {
+			if !statement.Principal.AWS.Contains("*") {
+				return false
+			}
+		} else if !statement.Principal.Match(args.AccountName) {
 			return false
 		}
 
@@ -79,6 +90,17 @@ func (statement BPStatement) isValid() error {
 		return Errorf("Action must not be empty")
 	}
 
+	// Policy variables are only allowed in Resource and Condition values.
+	if err := statement.Principal.validateNoVariables(); err != nil {
+		return err
+	}
+	if err := statement.Actions.validateNoVariables("Action"); err != nil {
+		return err
+	}
+	if err := statement.NotActions.validateNoVariables("NotAction"); err != nil {
+		return err
+	}
+
 	if len(statement.Resources) == 0 {
 		return Errorf("Resource must not be empty")
 	}
@@ -19,6 +19,7 @@ package policy
 
 import (
 	"encoding/json"
+	"errors"
 	"net"
 	"reflect"
 	"testing"
@@ -556,3 +557,110 @@ func TestBPStatementValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestBPStatementPolicyVariables(t *testing.T) {
+	testCases := []struct {
+		statement       BPStatement
+		expectedElement string
+	}{
+		{NewBPStatement("",
+			Allow,
+			NewPrincipal("${aws:username}"),
+			NewActionSet(GetObjectAction),
+			NewResourceSet(NewResource("mybucket/*")),
+			condition.NewFunctions(),
+		), "Principal"},
+		{NewBPStatement("",
+			Allow,
+			NewPrincipal("*"),
+			NewActionSet("s3:${aws:username}"),
+			NewResourceSet(NewResource("mybucket/*")),
+			condition.NewFunctions(),
+		), "Action"},
+		{NewBPStatement("",
+			Allow,
+			NewPrincipal("*"),
+			NewActionSet(GetObjectAction),
+			NewResourceSet(NewResource("mybucket/${aws:username}/*")),
+			condition.NewFunctions(),
+		), ""},
+	}
+
+	for i, testCase := range testCases {
+		err := testCase.statement.isValid()
+		if testCase.expectedElement == "" {
+			if err != nil {
+				t.Fatalf("case %v: unexpected error: %v", i+1, err)
+			}
+			continue
+		}
+
+		var verr VariableError
+		if !errors.As(err, &verr) {
+			t.Fatalf("case %v: expected VariableError, got: %v", i+1, err)
+		}
+		if verr.Element != testCase.expectedElement {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedElement, verr.Element)
+		}
+	}
+}
+
+func TestBPStatementIsAllowedAnonymous(t *testing.T) {
+	usernameFunc, err := condition.NewStringEqualsFunc("", condition.AWSUsername.ToKey(), "alice")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	statement1 := NewBPStatement("",
+		Allow,
+		NewPrincipal("*"),
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(),
+	)
+	statement2 := NewBPStatement("",
+		Allow,
+		NewPrincipal("alice"),
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(),
+	)
+	statement3 := NewBPStatement("",
+		Allow,
+		NewPrincipal("*"),
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(usernameFunc),
+	)
+
+	anonymousArgs := BucketPolicyArgs{
+		Action:          GetObjectAction,
+		BucketName:      "mybucket",
+		ObjectName:      "photo.jpg",
+		ConditionValues: map[string][]string{"username": {"alice"}},
+		Anonymous:       true,
+	}
+	userArgs := anonymousArgs
+	userArgs.AccountName = "alice"
+	userArgs.Anonymous = false
+
+	testCases := []struct {
+		statement      BPStatement
+		args           BucketPolicyArgs
+		expectedResult bool
+	}{
+		{statement1, anonymousArgs, true},
+		{statement1, userArgs, true},
+		{statement2, anonymousArgs, false},
+		{statement2, userArgs, true},
+		{statement3, anonymousArgs, false},
+		{statement3, userArgs, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.statement.IsAllowed(testCase.args)
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
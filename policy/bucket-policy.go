@@ -20,6 +20,8 @@ package policy
 import (
 	"encoding/json"
 	"io"
+
+	"github.com/trinet2005/oss-pkg/policy/condition"
 )
 
 // BucketPolicyArgs - arguments to policy to check whether it is allowed
@@ -31,6 +33,9 @@ type BucketPolicyArgs struct {
 	ConditionValues map[string][]string `json:"conditions"`
 	IsOwner         bool                `json:"owner"`
 	ObjectName      string              `json:"object"`
+	// Anonymous marks an unauthenticated request, which only principal "*"
+	// matches. Identity keys are handled as described for Args.Anonymous.
+	Anonymous bool `json:"anonymous"`
 }
 
 // BucketPolicy - bucket policy.
@@ -68,6 +73,46 @@ func (policy BucketPolicy) IsAllowed(args BucketPolicyArgs) bool {
 	return false
 }
 
+// identityConditionKeys - condition keys which restrict a statement to
+// known identities, see condition.IdentityKeys, or networks.
+var identityConditionKeys = func() condition.KeySet {
+	keySet := condition.NewKeySet(condition.AWSSourceIP.ToKey())
+	for _, name := range condition.IdentityKeys {
+		keySet.Add(name.ToKey())
+	}
+	return keySet
+}()
+
+// IsPublic - returns whether policy grants public access, i.e. any Allow
+// statement has the "*" principal and no condition restricting the
+// request to known identities or source IPs. Only StringEquals,
+// StringEqualsIgnoreCase, StringLike, BinaryEquals and IpAddress conditions
+// on those keys are considered restricting, but not IpAddress conditions on
+// a network of all addresses such as "0.0.0.0/0". Any other condition, such
+// as aws:Referer which is set by the client, is not. Deny statements are not
+// considered, hence policy may be reported as public although a Deny
+// statement blocks all access.
+func (policy BucketPolicy) IsPublic() bool {
+	for _, statement := range policy.Statements {
+		if statement.Effect != Allow || !statement.Principal.AWS.Contains("*") {
+			continue
+		}
+
+		restricted := false
+		for key := range statement.Conditions.RestrictingKeys() {
+			if identityConditionKeys.Match(key) {
+				restricted = true
+				break
+			}
+		}
+		if !restricted {
+			return true
+		}
+	}
+
+	return false
+}
+
 // IsEmpty - returns whether policy is empty or not.
 func (policy BucketPolicy) IsEmpty() bool {
 	return len(policy.Statements) == 0
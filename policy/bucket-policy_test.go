@@ -21,6 +21,7 @@ import (
 	"encoding/json"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/trinet2005/oss-pkg/policy/condition"
@@ -1149,3 +1150,136 @@ func TestBucketPolicyValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestBucketPolicyIsPublic(t *testing.T) {
+	testCases := []struct {
+		data           string
+		expectedResult bool
+	}{
+		// Anonymous read access.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        }
+    ]
+}`, true},
+		// Referer is set by the client, hence does not restrict access.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": {"AWS": ["*"]},
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {"StringLike": {"aws:Referer": "http://example.org/*"}}
+        }
+    ]
+}`, true},
+		// Negated conditions do not restrict access.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {"NotIpAddress": {"aws:SourceIp": "192.168.1.0/24"}}
+        }
+    ]
+}`, true},
+		// Restricted to a network.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {"IpAddress": {"aws:SourceIp": "192.168.1.0/24"}}
+        }
+    ]
+}`, false},
+		// Networks of all IPv4 or IPv6 addresses do not restrict access.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {"IpAddress": {"aws:SourceIp": "0.0.0.0/0"}}
+        }
+    ]
+}`, true},
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {"IpAddress": {"aws:SourceIp": ["2001:db8::/32", "::/0"]}}
+        }
+    ]
+}`, true},
+		// Restricted to known users.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {"StringEquals": {"aws:username": ["alice", "bob"]}}
+        }
+    ]
+}`, false},
+		// Specific principal.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Principal": {"AWS": ["arn:aws:iam::111122223333:root"]},
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        }
+    ]
+}`, false},
+		// Deny statements never grant access.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Deny",
+            "Principal": "*",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        }
+    ]
+}`, false},
+	}
+
+	for i, testCase := range testCases {
+		p, err := ParseBucketPolicyConfig(strings.NewReader(testCase.data), "mybucket")
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		result := p.IsPublic()
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompatWarning - describes a construct in a resource pattern which behaves
+// differently here than in common shell style globs.
+type CompatWarning struct {
+	// Offset - byte offset of the construct in the pattern.
+	Offset int
+	// Construct - the construct as written in the pattern.
+	Construct string
+	// Message - explanation of the difference.
+	Message string
+}
+
+// String - returns human readable form of the warning.
+func (w CompatWarning) String() string {
+	return fmt.Sprintf("offset %v: '%v': %v", w.Offset, w.Construct, w.Message)
+}
+
+// CheckPatternCompat - returns warnings for constructs in pattern whose
+// meaning differs from common globs, to help migrating glob rules to
+// resource patterns. Policy variables such as "${aws:username}" are not
+// reported.
+func CheckPatternCompat(pattern string) []CompatWarning {
+	var warnings []CompatWarning
+	warn := func(offset int, construct, message string) {
+		warnings = append(warnings, CompatWarning{Offset: offset, Construct: construct, Message: message})
+	}
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			j := i
+			for j < len(pattern) && pattern[j] == '*' {
+				j++
+			}
+			if j-i > 1 {
+				warn(i, pattern[i:j], "'**' has no special meaning, it is the same as '*' and matches across '/'")
+			} else {
+				warn(i, "*", "'*' matches across '/', not only within a single path segment")
+			}
+			i = j - 1
+		case '?':
+			warn(i, "?", "'?' also matches '/'")
+		case '[':
+			if j := strings.IndexByte(pattern[i+1:], ']'); j >= 0 {
+				warn(i, pattern[i:i+j+2], "character classes are not supported, '[...]' matches literally")
+				i += j + 1
+			}
+		case '$':
+			if strings.HasPrefix(pattern[i:], "${") {
+				if j := strings.IndexByte(pattern[i:], '}'); j >= 0 {
+					// Skip policy variable.
+					i += j
+				}
+			}
+		case '{':
+			if j := strings.IndexByte(pattern[i+1:], '}'); j >= 0 && strings.Contains(pattern[i+1:i+1+j], ",") {
+				warn(i, pattern[i:i+j+2], "brace expansion is not supported, '{...}' matches literally")
+				i += j + 1
+			}
+		case '\\':
+			// The following character keeps its meaning, hence it is not skipped.
+			warn(i, "\\", "escaping is not supported, '\\' matches literally")
+		}
+	}
+
+	return warnings
+}
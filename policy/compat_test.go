@@ -0,0 +1,70 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCheckPatternCompat(t *testing.T) {
+	testCases := []struct {
+		pattern        string
+		expectedResult []CompatWarning
+	}{
+		{"mybucket", nil},
+		{"mybucket/photos/2021/file.txt", nil},
+		{"mybucket/${aws:username}/file.txt", nil},
+		{"mybucket/{a}/file.txt", nil},
+		{"mybucket/[abc", nil},
+		{"mybucket/*", []CompatWarning{
+			{9, "*", "'*' matches across '/', not only within a single path segment"},
+		}},
+		{"mybucket/**/*.log", []CompatWarning{
+			{9, "**", "'**' has no special meaning, it is the same as '*' and matches across '/'"},
+			{12, "*", "'*' matches across '/', not only within a single path segment"},
+		}},
+		{"mybucket/file?.txt", []CompatWarning{
+			{13, "?", "'?' also matches '/'"},
+		}},
+		{"mybucket/file[0-9].txt", []CompatWarning{
+			{13, "[0-9]", "character classes are not supported, '[...]' matches literally"},
+		}},
+		{"mybucket/[*]", []CompatWarning{
+			{9, "[*]", "character classes are not supported, '[...]' matches literally"},
+		}},
+		{"mybucket/{jpg,png}", []CompatWarning{
+			{9, "{jpg,png}", "brace expansion is not supported, '{...}' matches literally"},
+		}},
+		{"mybucket/\\*", []CompatWarning{
+			{9, "\\", "escaping is not supported, '\\' matches literally"},
+			{10, "*", "'*' matches across '/', not only within a single path segment"},
+		}},
+		{"mybucket/${aws:username}/*", []CompatWarning{
+			{25, "*", "'*' matches across '/', not only within a single path segment"},
+		}},
+	}
+
+	for i, testCase := range testCases {
+		result := CheckPatternCompat(testCase.pattern)
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
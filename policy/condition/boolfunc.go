@@ -37,6 +37,15 @@ func (f booleanFunc) evaluate(values map[string][]string) bool {
 	if len(rvalues) == 0 {
 		return false
 	}
+	if f.k.Is(S3ObjectLockLegalHold) {
+		// Legal hold status is either "ON" or "OFF".
+		switch rvalues[0] {
+		case "ON":
+			return f.value == "true"
+		case "OFF":
+			return f.value == "false"
+		}
+	}
 	return f.value == rvalues[0]
 }
 
@@ -73,8 +82,8 @@ func (f booleanFunc) clone() Function {
 }
 
 func newBooleanFunc(key Key, values ValueSet, _ string) (Function, error) {
-	if !key.Is(AWSSecureTransport) {
-		return nil, fmt.Errorf("only %v key is allowed for %v condition", AWSSecureTransport, boolean)
+	if !key.Is(AWSSecureTransport) && !key.Is(S3ObjectLockLegalHold) {
+		return nil, fmt.Errorf("only %v and %v keys are allowed for %v condition", AWSSecureTransport, S3ObjectLockLegalHold, boolean)
 	}
 
 	if len(values) != 1 {
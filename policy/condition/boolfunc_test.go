@@ -33,6 +33,16 @@ func TestBooleanFuncEvaluate(t *testing.T) {
 		t.Fatalf("unexpected error. %v\n", err)
 	}
 
+	case3Function, err := newBooleanFunc(S3ObjectLockLegalHold.ToKey(), NewValueSet(NewBoolValue(true)), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case4Function, err := newBooleanFunc(S3ObjectLockLegalHold.ToKey(), NewValueSet(NewStringValue("false")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
 	testCases := []struct {
 		function       Function
 		values         map[string][]string
@@ -40,6 +50,15 @@ func TestBooleanFuncEvaluate(t *testing.T) {
 	}{
 		{case1Function, map[string][]string{"SecureTransport": {"true"}}, true},
 		{case2Function, map[string][]string{"SecureTransport": {"false"}}, true},
+		{case1Function, map[string][]string{"SecureTransport": {"false"}}, false},
+		{case3Function, map[string][]string{"object-lock-legal-hold": {"ON"}}, true},
+		{case3Function, map[string][]string{"object-lock-legal-hold": {"OFF"}}, false},
+		{case3Function, map[string][]string{"object-lock-legal-hold": {"true"}}, true},
+		{case3Function, map[string][]string{}, false},
+		{case4Function, map[string][]string{"object-lock-legal-hold": {"OFF"}}, true},
+		{case4Function, map[string][]string{"object-lock-legal-hold": {"ON"}}, false},
+		// "ON" and "OFF" are specific to the legal hold key.
+		{case1Function, map[string][]string{"SecureTransport": {"ON"}}, false},
 	}
 
 	for i, testCase := range testCases {
@@ -56,6 +56,14 @@ type Function interface {
 }
 
 // Functions - list of functions.
+//
+// Values of multi-valued keys such as "jwt:groups" are evaluated as a set,
+// their order and duplicates do not matter. To check that the set contains
+// a value use "ForAnyValue:StringEquals" with that value, with several
+// values it checks that the set contains any of them. Instead
+// "ForAllValues:StringEquals" checks that every value of the set is one of
+// the values, and holds for an empty set. Unqualified string operators
+// evaluate like ForAnyValue.
 type Functions []Function
 
 // Evaluate - evaluates all functions with given values map. Each function is evaluated
@@ -70,6 +78,82 @@ func (functions Functions) Evaluate(values map[string][]string) bool {
 	return true
 }
 
+// Defaults - default values of condition keys absent from request values.
+type Defaults map[string][]string
+
+// WithDefaults - returns defaults for condition keys absent from request
+// values. Keys are either condition keys, e.g. "s3:prefix", or names as
+// used in request values, e.g. "prefix".
+func WithDefaults(defaults map[string][]string) Defaults {
+	d := make(Defaults, len(defaults))
+	for k, v := range defaults {
+		if key, err := parseKey(k); err == nil {
+			k = key.Name()
+		}
+		d[k] = append([]string(nil), v...)
+	}
+	return d
+}
+
+// Values - returns request values with default values added for missing
+// keys. Request values override defaults, even when empty, and values is
+// not modified.
+func (defaults Defaults) Values(values map[string][]string) map[string][]string {
+	merged := make(map[string][]string, len(values)+len(defaults))
+	for k, v := range defaults {
+		merged[k] = v
+	}
+	for k, v := range values {
+		merged[k] = v
+	}
+	return merged
+}
+
+// Evaluate - evaluates functions like Functions.Evaluate with default
+// values substituted for missing keys before applying operators, hence a
+// defaulted key is not null for Null conditions.
+func (defaults Defaults) Evaluate(functions Functions, values map[string][]string) bool {
+	return functions.Evaluate(defaults.Values(values))
+}
+
+// Unsatisfied - returns functions which are not satisfied by given values
+// map, in their order. Unlike Evaluate, every function is evaluated.
+func (functions Functions) Unsatisfied(values map[string][]string) Functions {
+	var unsatisfied Functions
+	for _, f := range functions {
+		if !f.evaluate(values) {
+			unsatisfied = append(unsatisfied, f)
+		}
+	}
+
+	return unsatisfied
+}
+
+// And - returns functions satisfied only when both functions and other are
+// satisfied. As a condition block holds one value set per condition name
+// and key, false is returned when both use the same name and key with
+// different values.
+func (functions Functions) And(other Functions) (Functions, bool) {
+	result := functions.Clone()
+	for _, g := range other {
+		duplicate := false
+		for _, f := range functions {
+			if f.name() != g.name() || f.key() != g.key() {
+				continue
+			}
+			if f.String() != g.String() {
+				return nil, false
+			}
+			duplicate = true
+		}
+		if !duplicate {
+			result = append(result, g.clone())
+		}
+	}
+
+	return result, true
+}
+
 // Keys - returns list of keys used in all functions.
 func (functions Functions) Keys() KeySet {
 	keySet := NewKeySet()
@@ -81,6 +165,32 @@ func (functions Functions) Keys() KeySet {
 	return keySet
 }
 
+// RestrictingKeys - returns keys of condition functions which only hold
+// when the key has one of the condition values, i.e. StringEquals,
+// StringEqualsIgnoreCase, StringLike, BinaryEquals and IpAddress functions
+// without the ForAllValues qualifier. Negated functions and ForAllValues,
+// which hold for absent keys, are never restricting, nor are IpAddress
+// functions having a network of all addresses, i.e. "0.0.0.0/0" or "::/0".
+func (functions Functions) RestrictingKeys() KeySet {
+	keySet := NewKeySet()
+
+	for _, f := range functions {
+		n := f.name()
+		if n.qualifier == forAllValues {
+			continue
+		}
+		if ipf, ok := f.(*ipaddrFunc); ok && ipf.matchesAllAddresses() {
+			continue
+		}
+		switch n.name {
+		case stringEquals, stringEqualsIgnoreCase, stringLike, binaryEquals, ipAddress:
+			keySet.Add(f.key())
+		}
+	}
+
+	return keySet
+}
+
 // Clone clones Functions structure
 func (functions Functions) Clone() Functions {
 	funcs := []Function{}
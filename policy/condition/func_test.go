@@ -123,6 +123,74 @@ func TestFunctionsKeys(t *testing.T) {
 	}
 }
 
+func TestFunctionsRestrictingKeys(t *testing.T) {
+	func1, err := newNullFunc(S3XAmzCopySource.ToKey(), NewValueSet(NewBoolValue(true)), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func2, err := newIPAddressFunc(AWSSourceIP.ToKey(), NewValueSet(NewStringValue("192.168.1.0/24")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func3, err := newNotIPAddressFunc(AWSSourceIP.ToKey(), NewValueSet(NewStringValue("192.168.1.0/24")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func4, err := newStringEqualsFunc(AWSUsername.ToKey(), NewValueSet(NewStringValue("alice")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func5, err := newStringNotEqualsFunc(AWSUsername.ToKey(), NewValueSet(NewStringValue("alice")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func6, err := newStringLikeFunc(AWSGroups.ToKey(), NewValueSet(NewStringValue("dev*")), forAllValues)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func7, err := newStringLikeFunc(AWSGroups.ToKey(), NewValueSet(NewStringValue("dev*")), forAnyValue)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func8, err := newIPAddressFunc(AWSSourceIP.ToKey(), NewValueSet(NewStringValue("0.0.0.0/0")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func9, err := newIPAddressFunc(AWSSourceIP.ToKey(), NewValueSet(NewStringValue("192.168.1.0/24"), NewStringValue("::/0")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	testCases := []struct {
+		functions      Functions
+		expectedResult KeySet
+	}{
+		{NewFunctions(func1, func2), NewKeySet(AWSSourceIP.ToKey())},
+		// Networks of all addresses do not restrict.
+		{NewFunctions(func8), NewKeySet()},
+		{NewFunctions(func9), NewKeySet()},
+		{NewFunctions(func3, func5, func6), NewKeySet()},
+		{NewFunctions(func4, func7), NewKeySet(AWSUsername.ToKey(), AWSGroups.ToKey())},
+		{NewFunctions(), NewKeySet()},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.functions.RestrictingKeys()
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
 func TestFunctionsMarshalJSON(t *testing.T) {
 	func1, err := newStringLikeFunc(S3XAmzMetadataDirective.ToKey(), NewValueSet(NewStringValue("REPL*")), "")
 	if err != nil {
@@ -361,3 +429,146 @@ func TestFunctionsUnmarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestFunctionsUnsatisfied(t *testing.T) {
+	func1, err := newNullFunc(S3XAmzCopySource.ToKey(), NewValueSet(NewBoolValue(true)), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func2, err := newStringEqualsFunc(AWSUsername.ToKey(), NewValueSet(NewStringValue("alice")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	testCases := []struct {
+		functions      Functions
+		values         map[string][]string
+		expectedResult Functions
+	}{
+		{NewFunctions(), map[string][]string{}, nil},
+		{NewFunctions(func1, func2), map[string][]string{"username": {"alice"}}, nil},
+		{NewFunctions(func1, func2), map[string][]string{"username": {"bob"}}, NewFunctions(func2)},
+		{NewFunctions(func1, func2), map[string][]string{"x-amz-copy-source": {"mybucket/myobject"}}, NewFunctions(func1, func2)},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.functions.Unsatisfied(testCase.values)
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestFunctionsAnd(t *testing.T) {
+	func1, err := newNullFunc(S3XAmzCopySource.ToKey(), NewValueSet(NewBoolValue(true)), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func2, err := newStringEqualsFunc(AWSUsername.ToKey(), NewValueSet(NewStringValue("alice")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func3, err := newStringEqualsFunc(AWSUsername.ToKey(), NewValueSet(NewStringValue("bob")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func4, err := newStringNotEqualsFunc(AWSUsername.ToKey(), NewValueSet(NewStringValue("bob")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	testCases := []struct {
+		functions      Functions
+		other          Functions
+		expectedResult Functions
+		expectedOk     bool
+	}{
+		{NewFunctions(), NewFunctions(), NewFunctions(), true},
+		{NewFunctions(func1), NewFunctions(), NewFunctions(func1), true},
+		{NewFunctions(), NewFunctions(func2), NewFunctions(func2), true},
+		{NewFunctions(func1), NewFunctions(func2), NewFunctions(func1, func2), true},
+		{NewFunctions(func1, func2), NewFunctions(func2), NewFunctions(func1, func2), true},
+		{NewFunctions(func2), NewFunctions(func4), NewFunctions(func2, func4), true},
+		{NewFunctions(func2), NewFunctions(func3), nil, false},
+	}
+
+	for i, testCase := range testCases {
+		result, ok := testCase.functions.And(testCase.other)
+
+		if ok != testCase.expectedOk {
+			t.Fatalf("case %v: ok: expected: %v, got: %v\n", i+1, testCase.expectedOk, ok)
+		}
+
+		if !result.Equals(testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestDefaultsEvaluate(t *testing.T) {
+	func1, err := newStringEqualsFunc(S3XAmzStorageClass.ToKey(), NewValueSet(NewStringValue("STANDARD")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func2, err := newStringLikeFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("home/*")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func3, err := newNullFunc(S3XAmzServerSideEncryption.ToKey(), NewValueSet(NewBoolValue(true)), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	defaults := WithDefaults(map[string][]string{
+		// Condition keys are accepted as well as names.
+		"s3:x-amz-storage-class": {"STANDARD"},
+		"prefix":                 {"home/"},
+	})
+
+	testCases := []struct {
+		functions      Functions
+		values         map[string][]string
+		expectedResult bool
+	}{
+		// Default makes an otherwise failing condition pass.
+		{NewFunctions(func1), map[string][]string{}, true},
+		{NewFunctions(func1, func2), map[string][]string{}, true},
+		// Request values override defaults.
+		{NewFunctions(func1), map[string][]string{"x-amz-storage-class": {"GLACIER"}}, false},
+		{NewFunctions(func2), map[string][]string{"prefix": {"tmp/"}}, false},
+		{NewFunctions(func2), map[string][]string{"prefix": {"home/alice/"}}, true},
+		{NewFunctions(func2), map[string][]string{"prefix": {}}, false},
+		// Keys without defaults are missing.
+		{NewFunctions(func3), map[string][]string{}, true},
+		{NewFunctions(func1, func3), map[string][]string{"x-amz-server-side-encryption": {"AES256"}}, false},
+	}
+
+	for i, testCase := range testCases {
+		result := defaults.Evaluate(testCase.functions, testCase.values)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+
+	if NewFunctions(func1).Evaluate(map[string][]string{}) {
+		t.Fatalf("expected condition to fail without defaults")
+	}
+
+	values := map[string][]string{"prefix": {"tmp/"}}
+	merged := defaults.Values(values)
+	expected := map[string][]string{"x-amz-storage-class": {"STANDARD"}, "prefix": {"tmp/"}}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("expected: %v, got: %v\n", expected, merged)
+	}
+	if len(values) != 1 {
+		t.Fatalf("expected request values not to be modified, got: %v\n", values)
+	}
+}
@@ -68,6 +68,17 @@ func (f ipaddrFunc) evaluate(values map[string][]string) bool {
 	return result
 }
 
+// matchesAllAddresses - checks whether a network of the function holds all
+// addresses of its family, e.g. "0.0.0.0/0" or "::/0".
+func (f ipaddrFunc) matchesAllAddresses() bool {
+	for _, IPNet := range f.values {
+		if ones, _ := IPNet.Mask.Size(); ones == 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // key() - returns condition key which is used by this condition function.
 // Key is always AWSSourceIP.
 func (f ipaddrFunc) key() Key {
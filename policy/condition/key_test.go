@@ -37,6 +37,7 @@ func TestKeyIsValid(t *testing.T) {
 		{S3Prefix.ToKey(), true},
 		{S3Delimiter.ToKey(), true},
 		{S3MaxKeys.ToKey(), true},
+		{S3SignatureAge.ToKey(), true},
 		{AWSReferer.ToKey(), true},
 		{AWSSourceIP.ToKey(), true},
 		{ExistingObjectTag.ToKey(), true},
@@ -87,6 +88,7 @@ func TestKeyName(t *testing.T) {
 	}{
 		{S3XAmzCopySource.ToKey(), "x-amz-copy-source"},
 		{AWSReferer.ToKey(), "Referer"},
+		{S3SignatureAge.ToKey(), "signatureAge"},
 	}
 
 	for i, testCase := range testCases {
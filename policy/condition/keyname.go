@@ -106,8 +106,11 @@ const (
 	// Enables enforcement of a specific retain-until-date
 	S3ObjectLockRetainUntilDate KeyName = "s3:object-lock-retain-until-date"
 
-	// S3ObjectLockLegalHold - key representing object-local-legal-hold
-	// Enables enforcement of the specified object legal hold status
+	// S3ObjectLockLegalHold - key representing object-lock-legal-hold
+	// Enables enforcement of the specified object legal hold status, which
+	// is either "ON" or "OFF", for s3:PutObject and s3:PutObjectLegalHold
+	// APIs. Use StringEquals with "ON" or "OFF", or Bool where "true" means
+	// "ON" and "false" means "OFF".
 	S3ObjectLockLegalHold KeyName = "s3:object-lock-legal-hold"
 
 	// AWSReferer - key representing Referer header of any API.
@@ -144,8 +147,13 @@ const (
 	S3SignatureVersion KeyName = "s3:signatureversion"
 
 	// S3AuthType - optionally use this condition key to restrict incoming requests to use a specific authentication method.
+	// Presigned requests have the value "REST-QUERY-STRING".
 	S3AuthType KeyName = "s3:authType"
 
+	// S3SignatureAge - the length of time, in milliseconds, that a signature is valid in an authenticated request.
+	// Use it with numeric conditions, along with s3:authType, to restrict the lifetime of presigned URLs.
+	S3SignatureAge KeyName = "s3:signatureAge"
+
 	// Refer https://docs.aws.amazon.com/AmazonS3/latest/userguide/tagging-and-policies.html
 	ExistingObjectTag    KeyName = "s3:ExistingObjectTag"
 	RequestObjectTagKeys KeyName = "s3:RequestObjectTagKeys"
@@ -229,6 +237,7 @@ var JWTKeys = []KeyName{
 var AllSupportedKeys = append([]KeyName{
 	S3SignatureVersion,
 	S3AuthType,
+	S3SignatureAge,
 	S3XAmzCopySource,
 	S3XAmzServerSideEncryption,
 	S3XAmzServerSideEncryptionCustomerAlgorithm,
@@ -267,6 +276,7 @@ var AllSupportedKeys = append([]KeyName{
 var CommonKeys = append([]KeyName{
 	S3SignatureVersion,
 	S3AuthType,
+	S3SignatureAge,
 	S3XAmzContentSha256,
 	S3LocationConstraint,
 	AWSReferer,
@@ -301,3 +311,14 @@ var AllSupportedAdminKeys = append([]KeyName{
 	LDAPGroups,
 	// Add new supported condition keys.
 }, JWTKeys...)
+
+// IdentityKeys - keys identifying the authenticated principal of a request,
+// never present for anonymous requests.
+var IdentityKeys = append([]KeyName{
+	AWSUserID,
+	AWSUsername,
+	AWSGroups,
+	LDAPUser,
+	LDAPUsername,
+	LDAPGroups,
+}, JWTKeys...)
@@ -28,16 +28,77 @@ import (
 	"github.com/trinet2005/oss-pkg/wildcard"
 )
 
-func substitute(values map[string][]string) func(string) string {
-	return func(v string) string {
-		for _, key := range CommonKeys {
-			// Empty values are not supported for policy variables.
-			if rvalues, ok := values[key.Name()]; ok && rvalues[0] != "" {
-				v = strings.Replace(v, key.VarName(), rvalues[0], -1)
-			}
+// SubstituteVariables - returns v with policy variables of common keys, such
+// as "${aws:username}", replaced by the first value of the key in given
+// request values, e.g. "home/${aws:username}/*" becomes "home/johndoe/*".
+// Variables of keys absent from values, or having an empty first value, are
+// left as is.
+//
+// Only string operators, i.e. StringEquals, StringNotEquals,
+// StringEqualsIgnoreCase, StringNotEqualsIgnoreCase, StringLike and
+// StringNotLike including their ForAllValues and ForAnyValue forms,
+// substitute their policy values at evaluation. Values of other operators
+// are parsed when the policy is parsed and are compared as is.
+func SubstituteVariables(v string, values map[string][]string) string {
+	for _, key := range CommonKeys {
+		// Empty values are not supported for policy variables.
+		if rvalues := values[key.Name()]; len(rvalues) > 0 && rvalues[0] != "" {
+			v = strings.Replace(v, key.VarName(), rvalues[0], -1)
 		}
-		return v
 	}
+	return v
+}
+
+// KeyReference - returns the condition value referencing all values of key
+// in the request, e.g. "#{s3:ExistingObjectTag/owner}", allowing string
+// operators to compare one key against another. A string operator with a
+// key reference fails when the request has no value for the referenced
+// key, including its negated forms. StringLike and StringNotLike compare
+// referenced values literally, they are never wildcard patterns. Policy
+// variables such as
+// "${aws:username}" are unaffected and substitute the first value only.
+func KeyReference(key Key) string {
+	return keyReferencePrefix + key.String() + "}"
+}
+
+const keyReferencePrefix = "#{"
+
+// keyReference - returns the key referenced by v when v is exactly a key
+// reference of a supported key, see KeyReference.
+func keyReference(v string) (Key, bool) {
+	if !strings.HasPrefix(v, keyReferencePrefix) || !strings.HasSuffix(v, "}") {
+		return Key{}, false
+	}
+	key, err := parseKey(v[len(keyReferencePrefix) : len(v)-1])
+	if err != nil {
+		return Key{}, false
+	}
+	return key, true
+}
+
+// resolveValues - returns condition values to compare against, i.e. values
+// with policy variables substituted and, separately, all values of keys
+// referenced by key references in the request. It returns false if a
+// referenced key has no values in the request.
+func resolveValues(fvalues set.StringSet, values map[string][]string) (set.StringSet, set.StringSet, bool) {
+	nset := set.NewStringSet()
+	referenced := set.NewStringSet()
+	for _, v := range fvalues.ToSlice() {
+		key, ok := keyReference(v)
+		if !ok {
+			nset.Add(SubstituteVariables(v, values))
+			continue
+		}
+
+		rvalues := getValuesByKey(values, key)
+		if len(rvalues) == 0 {
+			return nil, nil, false
+		}
+		for _, rv := range rvalues {
+			referenced.Add(rv)
+		}
+	}
+	return nset, referenced, true
 }
 
 type stringFunc struct {
@@ -49,9 +110,8 @@ type stringFunc struct {
 	negate     bool
 }
 
-func (f stringFunc) eval(values map[string][]string) bool {
+func (f stringFunc) eval(values map[string][]string, fvalues set.StringSet) bool {
 	rvalues := set.CreateStringSet(getValuesByKey(values, f.k)...)
-	fvalues := f.values.ApplyFunc(substitute(values))
 	if f.ignoreCase {
 		rvalues = rvalues.ApplyFunc(strings.ToLower)
 		fvalues = fvalues.ApplyFunc(strings.ToLower)
@@ -64,7 +124,12 @@ func (f stringFunc) eval(values map[string][]string) bool {
 }
 
 func (f stringFunc) evaluate(values map[string][]string) bool {
-	result := f.eval(values)
+	fvalues, referenced, ok := resolveValues(f.values, values)
+	if !ok {
+		return false
+	}
+
+	result := f.eval(values, fvalues.Union(referenced))
 	if f.negate {
 		return !result
 	}
@@ -129,11 +194,11 @@ type stringLikeFunc struct {
 	stringFunc
 }
 
-func (f stringLikeFunc) eval(values map[string][]string) bool {
+func (f stringLikeFunc) eval(values map[string][]string, fvalues, referenced set.StringSet) bool {
 	rvalues := getValuesByKey(values, f.k)
-	fvalues := f.values.ApplyFunc(substitute(values))
 	for _, v := range rvalues {
-		matched := !fvalues.FuncMatch(wildcard.Match, v).IsEmpty()
+		// Referenced values come from the request, hence are never patterns.
+		matched := referenced.Contains(v) || !fvalues.FuncMatch(wildcard.Match, v).IsEmpty()
 		if f.n.qualifier == forAllValues {
 			if !matched {
 				return false
@@ -148,7 +213,12 @@ func (f stringLikeFunc) eval(values map[string][]string) bool {
 // evaluate() - evaluates to check whether value by Key in given values is wildcard
 // matching in condition values.
 func (f stringLikeFunc) evaluate(values map[string][]string) bool {
-	result := f.eval(values)
+	fvalues, referenced, ok := resolveValues(f.values, values)
+	if !ok {
+		return false
+	}
+
+	result := f.eval(values, fvalues, referenced)
 	if f.negate {
 		return !result
 	}
@@ -176,6 +246,11 @@ func valuesToStringSlice(n string, values ValueSet) ([]string, error) {
 
 func validateStringValues(n string, key Key, values set.StringSet) error {
 	for _, s := range values.ToSlice() {
+		// Key references are resolved at evaluation time.
+		if _, ok := keyReference(s); ok {
+			continue
+		}
+
 		switch {
 		case key.Is(S3XAmzCopySource):
 			bucket, object := path2BucketAndObject(s)
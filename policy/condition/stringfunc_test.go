@@ -98,6 +98,213 @@ func TestStringEqualsFuncEvaluate(t *testing.T) {
 	}
 }
 
+func TestStringFuncKeyReferenceEvaluate(t *testing.T) {
+	case1Function, err := newStringEqualsFunc(NewKey(RequestObjectTag, "owner"), NewValueSet(NewStringValue(KeyReference(NewKey(ExistingObjectTag, "owner")))), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case2Function, err := newStringNotEqualsFunc(S3XAmzCopySource.ToKey(), NewValueSet(NewStringValue("#{s3:prefix}")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case3Function, err := newStringLikeFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("#{jwt:groups}")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	// Policy variables keep substituting the first value only.
+	case4Function, err := newStringEqualsFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("${jwt:groups}")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	// Variables of keys other than common keys are compared literally.
+	case5Function, err := newStringEqualsFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("${s3:prefix}")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case6Function, err := newStringLikeFunc(NewKey(RequestObjectTag, "owner"), NewValueSet(NewStringValue(KeyReference(NewKey(ExistingObjectTag, "owner")))), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case7Function, err := newStringNotLikeFunc(NewKey(RequestObjectTag, "owner"), NewValueSet(NewStringValue(KeyReference(NewKey(ExistingObjectTag, "owner")))), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	testCases := []struct {
+		function       Function
+		values         map[string][]string
+		expectedResult bool
+	}{
+		{case1Function, map[string][]string{"RequestObjectTag/owner": {"alice"}, "ExistingObjectTag/owner": {"alice"}}, true},
+		{case1Function, map[string][]string{"RequestObjectTag/owner": {"bob"}, "ExistingObjectTag/owner": {"alice"}}, false},
+		// Missing referenced key fails the condition.
+		{case1Function, map[string][]string{"RequestObjectTag/owner": {"alice"}}, false},
+		{case1Function, map[string][]string{"RequestObjectTag/owner": {"#{s3:ExistingObjectTag/owner}"}}, false},
+
+		{case2Function, map[string][]string{"x-amz-copy-source": {"mybucket/myobject"}, "prefix": {"mybucket/myobject"}}, false},
+		{case2Function, map[string][]string{"x-amz-copy-source": {"mybucket/myobject"}, "prefix": {"mybucket/other"}}, true},
+		// Missing referenced key fails negated conditions too.
+		{case2Function, map[string][]string{"x-amz-copy-source": {"mybucket/myobject"}}, false},
+
+		// All values of the referenced key are compared.
+		{case3Function, map[string][]string{"prefix": {"art"}, "groups": {"prod", "art"}}, true},
+		{case3Function, map[string][]string{"prefix": {"dev"}, "groups": {"prod", "art"}}, false},
+		// Referenced values are compared literally, not as patterns.
+		{case3Function, map[string][]string{"prefix": {"art"}, "groups": {"*"}}, false},
+		{case3Function, map[string][]string{"prefix": {"art"}, "groups": {"a?t"}}, false},
+		{case3Function, map[string][]string{"prefix": {"a?t"}, "groups": {"a?t"}}, true},
+		{case6Function, map[string][]string{"RequestObjectTag/owner": {"mallory"}, "ExistingObjectTag/owner": {"*"}}, false},
+		{case6Function, map[string][]string{"RequestObjectTag/owner": {"*"}, "ExistingObjectTag/owner": {"*"}}, true},
+		{case7Function, map[string][]string{"RequestObjectTag/owner": {"mallory"}, "ExistingObjectTag/owner": {"*"}}, true},
+		{case7Function, map[string][]string{"RequestObjectTag/owner": {"mallory"}, "ExistingObjectTag/owner": {"mallory"}}, false},
+
+		{case4Function, map[string][]string{"prefix": {"eng"}, "groups": {"eng", "finance"}}, true},
+		{case4Function, map[string][]string{"prefix": {"finance"}, "groups": {"eng", "finance"}}, false},
+
+		{case5Function, map[string][]string{"prefix": {"finance"}}, false},
+		{case5Function, map[string][]string{"prefix": {"${s3:prefix}"}}, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.function.evaluate(testCase.values)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestSubstituteVariables(t *testing.T) {
+	testCases := []struct {
+		value          string
+		values         map[string][]string
+		expectedResult string
+	}{
+		{"home/${aws:username}/*", map[string][]string{"username": {"johndoe"}}, "home/johndoe/*"},
+		{"${aws:username}/${aws:username}", map[string][]string{"username": {"johndoe"}}, "johndoe/johndoe"},
+		{"${aws:username}/${jwt:sub}", map[string][]string{"username": {"johndoe"}, "sub": {"1234"}}, "johndoe/1234"},
+		{"home/${aws:username}/*", map[string][]string{"username": {"johndoe", "janedoe"}}, "home/johndoe/*"},
+		// Absent and empty values are not substituted.
+		{"home/${aws:username}/*", map[string][]string{}, "home/${aws:username}/*"},
+		{"home/${aws:username}/*", map[string][]string{"username": {""}}, "home/${aws:username}/*"},
+		{"home/${aws:username}/*", map[string][]string{"username": {}}, "home/${aws:username}/*"},
+		{"home/${aws:username}/*", nil, "home/${aws:username}/*"},
+		// Unsupported variables are left as is.
+		{"home/${aws:unknown}/*", map[string][]string{"unknown": {"johndoe"}}, "home/${aws:unknown}/*"},
+		{"home/public/*", map[string][]string{"username": {"johndoe"}}, "home/public/*"},
+	}
+
+	for i, testCase := range testCases {
+		result := SubstituteVariables(testCase.value, testCase.values)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestStringFuncSubstituteVariablesEvaluate(t *testing.T) {
+	case1Function, err := newStringLikeFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("home/${aws:username}/*")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case2Function, err := newStringEqualsFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("home/${aws:username}/")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case3Function, err := newStringNotLikeFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("home/${aws:username}/*")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case4Function, err := newStringEqualsIgnoreCaseFunc(S3Prefix.ToKey(), NewValueSet(NewStringValue("HOME/${aws:username}/")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	testCases := []struct {
+		function       Function
+		values         map[string][]string
+		expectedResult bool
+	}{
+		{case1Function, map[string][]string{"prefix": {"home/johndoe/docs/"}, "username": {"johndoe"}}, true},
+		{case1Function, map[string][]string{"prefix": {"home/janedoe/docs/"}, "username": {"johndoe"}}, false},
+		{case1Function, map[string][]string{"prefix": {"home/johndoe/docs/"}}, false},
+		{case2Function, map[string][]string{"prefix": {"home/johndoe/"}, "username": {"johndoe"}}, true},
+		{case2Function, map[string][]string{"prefix": {"home/johndoe/"}, "username": {"janedoe"}}, false},
+		{case3Function, map[string][]string{"prefix": {"home/johndoe/docs/"}, "username": {"johndoe"}}, false},
+		{case3Function, map[string][]string{"prefix": {"home/janedoe/docs/"}, "username": {"johndoe"}}, true},
+		{case4Function, map[string][]string{"prefix": {"Home/JohnDoe/"}, "username": {"johndoe"}}, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.function.evaluate(testCase.values)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestStringFuncSetMembershipEvaluate(t *testing.T) {
+	case1Function, err := newStringEqualsFunc(JWTGroups.ToKey(), NewValueSet(NewStringValue("admins")), forAnyValue)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case2Function, err := newStringEqualsFunc(JWTGroups.ToKey(), NewValueSet(NewStringValue("admins"), NewStringValue("ops")), forAnyValue)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case3Function, err := newStringEqualsFunc(JWTGroups.ToKey(), NewValueSet(NewStringValue("admins"), NewStringValue("ops")), forAllValues)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	case4Function, err := newStringEqualsFunc(JWTGroups.ToKey(), NewValueSet(NewStringValue("admins")), "")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	testCases := []struct {
+		function       Function
+		groups         [][]string
+		expectedResult bool
+	}{
+		// Set contains the value, in any order and with duplicates.
+		{case1Function, [][]string{{"admins"}, {"dev", "admins"}, {"admins", "dev"}, {"dev", "admins", "ops", "admins"}}, true},
+		{case1Function, [][]string{{}, {"dev"}, {"dev", "ops"}, {"Admins"}}, false},
+		// Set contains any of the values.
+		{case2Function, [][]string{{"ops"}, {"dev", "ops"}, {"admins", "dev"}, {"ops", "admins"}}, true},
+		{case2Function, [][]string{{}, {"dev"}, {"dev", "qa"}}, false},
+		// Every value of the set is one of the values.
+		{case3Function, [][]string{{}, {"ops"}, {"admins", "ops"}, {"ops", "admins", "ops"}}, true},
+		{case3Function, [][]string{{"dev"}, {"admins", "dev"}, {"dev", "admins"}}, false},
+		// Unqualified operators evaluate like ForAnyValue.
+		{case4Function, [][]string{{"admins"}, {"dev", "admins"}, {"admins", "dev"}}, true},
+		{case4Function, [][]string{{}, {"dev", "ops"}}, false},
+	}
+
+	for i, testCase := range testCases {
+		for _, groups := range testCase.groups {
+			result := testCase.function.evaluate(map[string][]string{"groups": groups})
+
+			if result != testCase.expectedResult {
+				t.Fatalf("case %v: %v: expected: %v, got: %v\n", i+1, groups, testCase.expectedResult, result)
+			}
+		}
+	}
+}
+
 func TestStringNotEqualsFuncEvaluate(t *testing.T) {
 	case1Function, err := newStringNotEqualsFunc(S3XAmzCopySource.ToKey(), NewValueSet(NewStringValue("mybucket/myobject")), "")
 	if err != nil {
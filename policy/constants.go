@@ -132,3 +132,49 @@ var DefaultPolicies = []struct {
 		},
 	},
 }
+
+// AllowAll - returns a policy allowing all S3, admin and KMS actions on all
+// resources.
+func AllowAll() Policy {
+	return allActionsPolicy(Allow)
+}
+
+// DenyAll - returns a policy explicitly denying all S3, admin and KMS actions
+// on all resources, which overrides any allow of policies it is merged with.
+func DenyAll() Policy {
+	return allActionsPolicy(Deny)
+}
+
+// allActionsPolicy - returns a policy applying effect to all actions.
+func allActionsPolicy(effect Effect) Policy {
+	return Policy{
+		Version: DefaultVersion,
+		Statements: []Statement{
+			NewStatement("", effect, NewActionSet(AllAdminActions), NewResourceSet(), condition.NewFunctions()),
+			NewStatement("", effect, NewActionSet(AllKMSActions), NewResourceSet(), condition.NewFunctions()),
+			NewStatement("", effect, NewActionSet(AllActions), NewResourceSet(NewResource("*")), condition.NewFunctions()),
+		},
+	}
+}
+
+// NewAllowPolicy - returns a policy with a single statement allowing given
+// actions, e.g. "s3:GetObject", on given resource patterns, e.g.
+// "mybucket/*", like NewResource. The policy is not validated.
+func NewAllowPolicy(actions, resources []string) Policy {
+	actionSet := NewActionSet()
+	for _, action := range actions {
+		actionSet.Add(Action(action))
+	}
+
+	resourceSet := NewResourceSet()
+	for _, resource := range resources {
+		resourceSet.Add(NewResource(resource))
+	}
+
+	return Policy{
+		Version: DefaultVersion,
+		Statements: []Statement{
+			NewStatement("", Allow, actionSet, resourceSet, condition.NewFunctions()),
+		},
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// Decision - outcome of evaluating a request against policies.
+type Decision string
+
+const (
+	// DecisionAllow - request is allowed.
+	DecisionAllow Decision = "Allow"
+
+	// DecisionExplicitDeny - request is denied by a Deny statement.
+	DecisionExplicitDeny Decision = "ExplicitDeny"
+
+	// DecisionImplicitDeny - request is denied as no statement allows it.
+	DecisionImplicitDeny Decision = "ImplicitDeny"
+)
+
+// IsAllowed - returns whether decision allows the request.
+func (d Decision) IsAllowed() bool {
+	return d == DecisionAllow
+}
+
+// Explanation - describes which statement decided a request and why.
+type Explanation struct {
+	Decision Decision
+	// StatementIndex is the index of the deciding statement in the
+	// policy, -1 when no statement decided.
+	StatementIndex int
+	// SID is the Sid of the deciding statement, if any.
+	SID ID
+	// Reason is a human readable reason for the decision.
+	Reason string
+}
+
+func explainStatement(i int, statement Statement) string {
+	if statement.SID != "" {
+		return fmt.Sprintf("statement %d (Sid '%s')", i+1, statement.SID)
+	}
+	return fmt.Sprintf("statement %d", i+1)
+}
+
+// Explain - evaluates args the same way as IsAllowed and explains the
+// decision.
+func (iamp Policy) Explain(args Args) Explanation {
+	decision, i, _ := iamp.evaluate(nil, args)
+	if i >= 0 {
+		statement := iamp.Statements[i]
+		reason := fmt.Sprintf("%s allows action '%s'", explainStatement(i, statement), args.Action)
+		if decision == DecisionExplicitDeny {
+			reason = fmt.Sprintf("%s explicitly denies action '%s'", explainStatement(i, statement), args.Action)
+		}
+		return Explanation{
+			Decision:       decision,
+			StatementIndex: i,
+			SID:            statement.SID,
+			Reason:         reason,
+		}
+	}
+
+	explanation := Explanation{
+		Decision:       decision,
+		StatementIndex: -1,
+	}
+	switch {
+	case decision == DecisionImplicitDeny:
+		explanation.Reason = fmt.Sprintf("no statement allows action '%s'", args.Action)
+	case args.DenyOnly:
+		explanation.Reason = "no statement explicitly denies the request"
+	default:
+		explanation.Reason = "owner is allowed by default"
+	}
+	return explanation
+}
+
+// CombinedDecision - evaluates args against layered policies, e.g. an
+// identity policy, a bucket policy and a service control policy, each the
+// same way as Explain. The rule is:
+//   - an explicit Deny by any policy denies the request, as DecisionExplicitDeny;
+//   - otherwise the request is allowed only when every policy allows it;
+//   - otherwise, including when no policy is given, it is DecisionImplicitDeny.
+//
+// Every policy is a required layer. Unlike AWS, an Allow of a bucket policy
+// alone does not grant access in absence of an identity policy Allow, pass
+// only the policies which must all allow the request.
+func CombinedDecision(args Args, policies ...Policy) Decision {
+	if len(policies) == 0 {
+		return DecisionImplicitDeny
+	}
+
+	decision := DecisionAllow
+	for _, p := range policies {
+		switch p.Explain(args).Decision {
+		case DecisionExplicitDeny:
+			return DecisionExplicitDeny
+		case DecisionImplicitDeny:
+			decision = DecisionImplicitDeny
+		}
+	}
+
+	return decision
+}
+
+// PermissionMatrix - returns the decision of the policy, as per Explain, for
+// every pair of actions and resources, indexed by action then resource.
+// Resources are buckets or objects, e.g. "mybucket" or "mybucket/myobject",
+// evaluated as args of the bucket and object, whose bucket and object names
+// are ignored. Policy variables of statement resources are substituted once
+// for the matrix.
+func (iamp Policy) PermissionMatrix(actions, resources []string, args Args) [][]Decision {
+	if args.Anonymous {
+		args = anonymousArgs(args)
+	}
+
+	argsResources := toArgsResources(resources)
+
+	patterns := make([][]Resource, len(iamp.Statements))
+	for i, statement := range iamp.Statements {
+		patterns[i] = statement.substitutedResources(args.ConditionValues)
+	}
+
+	initial := DecisionImplicitDeny
+	if args.DenyOnly || args.IsOwner {
+		initial = DecisionAllow
+	}
+
+	matrix := make([][]Decision, len(actions))
+	for i, action := range actions {
+		args.Action = Action(action)
+
+		row := make([]Decision, len(resources))
+		for j := range row {
+			row[j] = initial
+		}
+
+		for k, statement := range iamp.Statements {
+			for j, matched := range statement.matchResources(patterns[k], argsResources, args) {
+				if !matched {
+					continue
+				}
+				if statement.Effect == Deny {
+					row[j] = DecisionExplicitDeny
+				} else if statement.Effect == Allow && row[j] == DecisionImplicitDeny {
+					row[j] = DecisionAllow
+				}
+			}
+		}
+		matrix[i] = row
+	}
+
+	return matrix
+}
+
+// TestAccess - parses policy document and evaluates args against it,
+// returning the decision along with its explanation.
+func TestAccess(doc []byte, args Args) (Decision, Explanation, error) {
+	p, err := ParseConfig(bytes.NewReader(doc))
+	if err != nil {
+		return DecisionImplicitDeny, Explanation{}, err
+	}
+
+	explanation := p.Explain(args)
+	return explanation.Decision, explanation, nil
+}
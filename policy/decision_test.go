@@ -0,0 +1,209 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPolicyTestAccess(t *testing.T) {
+	doc := []byte(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Sid": "AllowRead",
+            "Effect": "Allow",
+            "Action": ["s3:GetObject", "s3:PutObject"],
+            "Resource": "arn:aws:s3:::mybucket/*"
+        },
+        {
+            "Sid": "DenyPutSecrets",
+            "Effect": "Deny",
+            "Action": "s3:PutObject",
+            "Resource": "arn:aws:s3:::mybucket/secrets/*"
+        }
+    ]
+}`)
+
+	testCases := []struct {
+		doc                    []byte
+		args                   Args
+		expectedDecision       Decision
+		expectedStatementIndex int
+		expectedSID            ID
+		expectErr              bool
+	}{
+		{doc, Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, DecisionAllow, 0, "AllowRead", false},
+		{doc, Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "secrets/key"}, DecisionExplicitDeny, 1, "DenyPutSecrets", false},
+		{doc, Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "secrets/key", IsOwner: true}, DecisionExplicitDeny, 1, "DenyPutSecrets", false},
+		{doc, Args{Action: DeleteObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, DecisionImplicitDeny, -1, "", false},
+		{doc, Args{Action: DeleteObjectAction, BucketName: "mybucket", ObjectName: "myobject", IsOwner: true}, DecisionAllow, -1, "", false},
+		{doc, Args{Action: DeleteObjectAction, BucketName: "mybucket", ObjectName: "myobject", DenyOnly: true}, DecisionAllow, -1, "", false},
+		{[]byte(`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow"}]}`), Args{Action: GetObjectAction}, DecisionImplicitDeny, 0, "", true},
+		{[]byte(`{"Version": `), Args{Action: GetObjectAction}, DecisionImplicitDeny, 0, "", true},
+	}
+
+	for i, testCase := range testCases {
+		decision, explanation, err := TestAccess(testCase.doc, testCase.args)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+		if decision != testCase.expectedDecision {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedDecision, decision)
+		}
+		if testCase.expectErr {
+			continue
+		}
+
+		if explanation.Decision != decision {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, decision, explanation.Decision)
+		}
+		if explanation.StatementIndex != testCase.expectedStatementIndex {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedStatementIndex, explanation.StatementIndex)
+		}
+		if explanation.SID != testCase.expectedSID {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedSID, explanation.SID)
+		}
+		if explanation.Reason == "" {
+			t.Fatalf("case %v: expected non-empty reason", i+1)
+		}
+	}
+}
+
+func TestCombinedDecision(t *testing.T) {
+	parse := func(doc string) Policy {
+		p, err := ParseConfig(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return *p
+	}
+
+	identity := parse(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject", "s3:DeleteObject"], "Resource": "arn:aws:s3:::mybucket/*"}
+    ]
+}`)
+	bucket := parse(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"], "Resource": "arn:aws:s3:::mybucket/*"},
+        {"Effect": "Deny", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::mybucket/secrets/*"}
+    ]
+}`)
+	scp := parse(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Effect": "Allow", "Action": "s3:*", "Resource": "arn:aws:s3:::*"},
+        {"Effect": "Deny", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/restricted/*"}
+    ]
+}`)
+
+	object := func(action Action, object string) Args {
+		return Args{Action: action, BucketName: "mybucket", ObjectName: object}
+	}
+
+	testCases := []struct {
+		args             Args
+		policies         []Policy
+		expectedDecision Decision
+	}{
+		{object(GetObjectAction, "a.txt"), []Policy{identity, bucket, scp}, DecisionAllow},
+		{object(PutObjectAction, "a.txt"), []Policy{identity, bucket, scp}, DecisionAllow},
+		// Deny overrides allows of other layers.
+		{object(PutObjectAction, "secrets/a.txt"), []Policy{identity, bucket, scp}, DecisionExplicitDeny},
+		{object(GetObjectAction, "restricted/a.txt"), []Policy{identity, bucket, scp}, DecisionExplicitDeny},
+		{object(GetObjectAction, "restricted/a.txt"), []Policy{scp, identity}, DecisionExplicitDeny},
+		// Deny wins over a missing allow of another layer.
+		{Args{Action: PutObjectAction, BucketName: "yourbucket", ObjectName: "a.txt"}, []Policy{identity, bucket, DenyAll()}, DecisionExplicitDeny},
+		// Every layer must allow.
+		{object(DeleteObjectAction, "a.txt"), []Policy{identity, bucket, scp}, DecisionImplicitDeny},
+		{object(DeleteObjectAction, "a.txt"), []Policy{identity, scp}, DecisionAllow},
+		{Args{Action: GetObjectAction, BucketName: "yourbucket", ObjectName: "a.txt"}, []Policy{identity, scp}, DecisionImplicitDeny},
+		{object(GetObjectAction, "a.txt"), []Policy{identity, {Version: DefaultVersion}}, DecisionImplicitDeny},
+		{object(GetObjectAction, "a.txt"), []Policy{identity}, DecisionAllow},
+		{object(GetObjectAction, "a.txt"), nil, DecisionImplicitDeny},
+	}
+
+	for i, testCase := range testCases {
+		decision := CombinedDecision(testCase.args, testCase.policies...)
+
+		if decision != testCase.expectedDecision {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedDecision, decision)
+		}
+	}
+}
+
+func TestPolicyPermissionMatrix(t *testing.T) {
+	p, err := ParseConfig(strings.NewReader(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Effect": "Allow", "Action": ["s3:GetObject", "s3:PutObject"], "Resource": "arn:aws:s3:::mybucket/*"},
+        {"Effect": "Allow", "Action": "s3:ListBucket", "Resource": "arn:aws:s3:::mybucket"},
+        {"Effect": "Allow", "Action": "s3:*", "Resource": "arn:aws:s3:::mybucket/home/${aws:username}/*"},
+        {"Effect": "Deny", "Action": "s3:PutObject", "Resource": "arn:aws:s3:::mybucket/secrets/*"}
+    ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actions := []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:ListBucket"}
+	resources := []string{"mybucket", "mybucket/a.txt", "mybucket/secrets/key", "mybucket/home/alice/a.txt", "yourbucket/a.txt"}
+	args := Args{ConditionValues: map[string][]string{"username": {"alice"}}}
+
+	A, E, I := DecisionAllow, DecisionExplicitDeny, DecisionImplicitDeny
+	expectedResult := [][]Decision{
+		{A, A, A, A, I},
+		{A, A, E, A, I},
+		{I, I, I, A, I},
+		{A, I, I, A, I},
+	}
+
+	result := p.PermissionMatrix(actions, resources, args)
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, result)
+	}
+
+	for _, args := range []Args{args, {}, {IsOwner: true}, {DenyOnly: true, ConditionValues: args.ConditionValues}} {
+		result := p.PermissionMatrix(actions, resources, args)
+		for i, action := range actions {
+			for j, resource := range resources {
+				cellArgs := args
+				cellArgs.Action = Action(action)
+				tokens := strings.SplitN(resource, "/", 2)
+				cellArgs.BucketName = tokens[0]
+				if len(tokens) == 2 {
+					cellArgs.ObjectName = tokens[1]
+				}
+				if decision := p.Explain(cellArgs).Decision; result[i][j] != decision {
+					t.Fatalf("%v %v %v: expected: %v, got: %v", args, action, resource, decision, result[i][j])
+				}
+			}
+		}
+	}
+
+	if result := p.PermissionMatrix(nil, resources, args); len(result) != 0 {
+		t.Fatalf("expected empty matrix, got: %v", result)
+	}
+}
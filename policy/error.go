@@ -43,3 +43,18 @@ func (e Error) Error() string {
 	}
 	return e.err.Error()
 }
+
+// VariableError is the error returned when a policy variable such as
+// "${aws:username}" is used in an element which does not support policy
+// variables.
+type VariableError struct {
+	// Element is the policy element, e.g. "Action" or "Principal".
+	Element string
+	// Value is the offending value of the element.
+	Value string
+}
+
+// Error 'error' compatible method.
+func (e VariableError) Error() string {
+	return fmt.Sprintf("policy variables are not allowed in %v '%v'", e.Element, e.Value)
+}
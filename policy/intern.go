@@ -0,0 +1,67 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// internEnabled - whether parseResource interns resource patterns.
+var internEnabled atomic.Bool
+
+// internPool - pool of interned strings, keyed and valued by the string.
+var internPool sync.Map
+
+// EnableInterning - makes resource parsing intern patterns, so identical
+// patterns of all parsed policies share one backing string. This saves
+// memory when many policies repeat the same resources, at the cost of a
+// pool lookup per parsed resource. Interned strings are never released,
+// the pool grows with every distinct pattern until DisableInterning is
+// called, hence interning suits deployments with a bounded set of patterns.
+// It is safe to call concurrently with parsing.
+func EnableInterning() {
+	internEnabled.Store(true)
+}
+
+// DisableInterning - stops interning of resource patterns and releases the
+// pool. Already parsed resources keep their strings.
+func DisableInterning() {
+	internEnabled.Store(false)
+	internPool.Range(func(key, _ interface{}) bool {
+		internPool.Delete(key)
+		return true
+	})
+}
+
+// intern - returns the pooled string equal to s if interning is enabled,
+// else s.
+func intern(s string) string {
+	if !internEnabled.Load() {
+		return s
+	}
+
+	if v, ok := internPool.Load(s); ok {
+		return v.(string)
+	}
+
+	// Clone so the pool does not pin the input, e.g. a whole resource ARN.
+	v, _ := internPool.LoadOrStore(s, strings.Clone(s))
+	return v.(string)
+}
@@ -0,0 +1,61 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"reflect"
+	"testing"
+	"unsafe"
+)
+
+// stringData - returns the address of the backing storage of s.
+func stringData(s string) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestEnableInterning(t *testing.T) {
+	EnableInterning()
+	defer DisableInterning()
+
+	// Copy the ARNs so they do not share constant storage.
+	arn := "arn:aws:s3:::mybucket/photos/*"
+	resource1, err := parseResource(string([]byte(arn)))
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+	resource2, err := parseResource(string([]byte(arn)))
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	if stringData(resource1.Pattern) != stringData(resource2.Pattern) {
+		t.Fatalf("expected shared pattern storage")
+	}
+
+	DisableInterning()
+	resource3, err := parseResource(string([]byte(arn)))
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+	if stringData(resource1.Pattern) == stringData(resource3.Pattern) {
+		t.Fatalf("expected distinct pattern storage")
+	}
+	if resource1.Pattern != resource3.Pattern {
+		t.Fatalf("expected: %v, got: %v", resource1.Pattern, resource3.Pattern)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+
+	"github.com/trinet2005/oss-pkg/wildcard"
+)
+
+// Intersect - returns a policy allowing only what both the policy and other
+// allow, and denying what either denies. Deny statements of both policies
+// are kept as is, while each pair of Allow statements is combined into one
+// statement allowing their common actions and resources under both their
+// conditions. The result never allows more than both policies, but may
+// allow less as common actions and resources are approximated:
+//
+//   - an action or resource is common if its pattern is contained in a
+//     pattern of the other statement, see wildcard.MatchPattern, hence
+//     overlapping patterns which do not contain each other, e.g. "s3:Get*"
+//     and "s3:*Object", have nothing in common.
+//   - resources using policy variables are only common with patterns
+//     matching the variable literally, e.g. "mybucket/*" and
+//     "mybucket/${aws:username}/*".
+//   - statements using the same condition operator and key with different
+//     values are not combined.
+//
+// Sids are dropped as combined statements have no single origin.
+func (iamp Policy) Intersect(other Policy) Policy {
+	result := Policy{Version: iamp.Version}
+	if result.Version == "" {
+		result.Version = other.Version
+	}
+
+	for _, p := range []Policy{iamp, other} {
+		for _, statement := range p.Statements {
+			if statement.Effect == Deny {
+				statement = statement.Clone()
+				statement.SID = ""
+				result.Statements = append(result.Statements, statement)
+			}
+		}
+	}
+
+	for _, a := range iamp.Statements {
+		if a.Effect != Allow {
+			continue
+		}
+		for _, b := range other.Statements {
+			if b.Effect != Allow {
+				continue
+			}
+			if statement, ok := intersectStatements(a, b); ok {
+				result.Statements = append(result.Statements, statement)
+			}
+		}
+	}
+
+	result.dropDuplicateStatements()
+	return result
+}
+
+// intersectStatements - returns a statement allowing what both allow
+// statements allow, false if it cannot be represented or allows nothing.
+func intersectStatements(a, b Statement) (Statement, bool) {
+	var actions ActionSet
+	switch {
+	case len(a.Actions) == 0:
+		actions = b.Actions.Clone()
+	case len(b.Actions) == 0:
+		actions = a.Actions.Clone()
+	default:
+		actions = intersectActions(a.Actions, b.Actions)
+		if len(actions) == 0 {
+			return Statement{}, false
+		}
+	}
+
+	notActions := a.NotActions.Clone()
+	for action := range b.NotActions {
+		notActions.Add(action)
+	}
+
+	var resources ResourceSet
+	switch {
+	case len(a.Resources) == 0:
+		resources = b.Resources.Clone()
+	case len(b.Resources) == 0:
+		resources = a.Resources.Clone()
+	default:
+		resources = intersectResources(a.Resources, b.Resources)
+		if len(resources) == 0 {
+			return Statement{}, false
+		}
+	}
+
+	conditions, ok := a.Conditions.And(b.Conditions)
+	if !ok {
+		return Statement{}, false
+	}
+
+	statement := NewStatement("", Allow, actions, resources, conditions)
+	if len(notActions) != 0 {
+		statement.NotActions = notActions
+	}
+	return statement, true
+}
+
+// intersectActions - returns actions of either set contained in a pattern
+// of the other set.
+func intersectActions(a, b ActionSet) ActionSet {
+	actions := NewActionSet()
+	for action := range a {
+		if containsAction(b, action) {
+			actions.Add(action)
+		}
+	}
+	for action := range b {
+		if containsAction(a, action) {
+			actions.Add(action)
+		}
+	}
+	return actions
+}
+
+// intersectResources - returns resources of either set contained in a
+// pattern of the other set.
+func intersectResources(a, b ResourceSet) ResourceSet {
+	resources := NewResourceSet()
+	for resource := range a {
+		if containsResource(b, resource) {
+			resources.Add(resource)
+		}
+	}
+	for resource := range b {
+		if containsResource(a, resource) {
+			resources.Add(resource)
+		}
+	}
+	return resources
+}
+
+// containsAction - checks whether action is contained in a pattern of the
+// action set.
+func containsAction(actionSet ActionSet, action Action) bool {
+	for pattern := range actionSet {
+		if wildcard.MatchPattern(string(pattern), string(action)) {
+			return true
+		}
+		// GetObjectVersion enables GetObject implicitly, see Match.
+		if pattern == GetObjectVersionAction && action == GetObjectAction {
+			return true
+		}
+	}
+	return false
+}
+
+// containsResource - checks whether resource is contained in a pattern of
+// the resource set. A resource with policy variables is contained only by an
+// identical pattern or by a pattern without variables matching any value of
+// its variables, i.e. with a '*' covering each variable.
+func containsResource(resourceSet ResourceSet, resource Resource) bool {
+	if !resource.matchable() {
+		return false
+	}
+
+	contained := resource.Pattern
+	for _, v := range policyVariables(resource.Pattern) {
+		contained = strings.ReplaceAll(contained, v, "*")
+	}
+
+	for pattern := range resourceSet {
+		if !pattern.matchable() {
+			continue
+		}
+		if pattern.Pattern == resource.Pattern {
+			return true
+		}
+		if !hasPolicyVariable(pattern.Pattern) && wildcard.MatchPattern(pattern.Pattern, contained) {
+			return true
+		}
+	}
+	return false
+}
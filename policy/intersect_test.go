@@ -0,0 +1,222 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/trinet2005/oss-pkg/policy/condition"
+)
+
+func TestPolicyIntersect(t *testing.T) {
+	policy1, err := ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "ReadBuckets",
+      "Effect": "Allow",
+      "Action": ["s3:GetObject", "s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::mybucket", "arn:aws:s3:::mybucket/*", "arn:aws:s3:::otherbucket/*"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	policy2, err := ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "ReadPhotos",
+      "Effect": "Allow",
+      "Action": ["s3:Get*", "s3:ListAllMyBuckets"],
+      "Resource": ["arn:aws:s3:::mybucket/photos/*"]
+    },
+    {
+      "Sid": "ListBucket",
+      "Effect": "Allow",
+      "Action": ["s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::mybucket"],
+      "Condition": {"StringEquals": {"s3:prefix": ["photos/"]}}
+    },
+    {
+      "Sid": "DenyPrivate",
+      "Effect": "Deny",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::mybucket/photos/private/*"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	func1, err := condition.NewStringEqualsFunc("", condition.S3Prefix.ToKey(), "photos/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedResult := Policy{
+		Version: DefaultVersion,
+		Statements: []Statement{
+			NewStatement("",
+				Deny,
+				NewActionSet(GetObjectAction),
+				NewResourceSet(NewResource("mybucket/photos/private/*")),
+				condition.NewFunctions(),
+			),
+			NewStatement("",
+				Allow,
+				NewActionSet(GetObjectAction),
+				NewResourceSet(NewResource("mybucket/photos/*")),
+				condition.NewFunctions(),
+			),
+			NewStatement("",
+				Allow,
+				NewActionSet(ListBucketAction),
+				NewResourceSet(NewResource("mybucket")),
+				condition.NewFunctions(func1),
+			),
+		},
+	}
+
+	result := policy1.Intersect(*policy2)
+	if !result.Equals(expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, result)
+	}
+	if err = result.Validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reverse := policy2.Intersect(*policy1); !reverse.Equals(expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, reverse)
+	}
+
+	testCases := []struct {
+		args           Args
+		expectedResult bool
+	}{
+		{Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "photos/a.jpg"}, true},
+		{Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "photos/private/a.jpg"}, false},
+		{Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "docs/a.txt"}, false},
+		{Args{Action: GetObjectAction, BucketName: "otherbucket", ObjectName: "photos/a.jpg"}, false},
+		{Args{Action: GetBucketLocationAction, BucketName: "mybucket", ObjectName: "photos/a.jpg"}, false},
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: map[string][]string{"prefix": {"photos/"}}}, true},
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: map[string][]string{"prefix": {"docs/"}}}, false},
+		{Args{Action: ListAllMyBucketsAction}, false},
+	}
+
+	for i, testCase := range testCases {
+		got := result.IsAllowed(testCase.args)
+		if got != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, got)
+		}
+
+		// The intersection never allows more than both policies.
+		if got && !(policy1.IsAllowed(testCase.args) && policy2.IsAllowed(testCase.args)) {
+			t.Fatalf("case %v: expected intersection not to allow more than both policies", i+1)
+		}
+	}
+}
+
+func TestPolicyIntersectNotRepresentable(t *testing.T) {
+	func1, err := condition.NewStringEqualsFunc("", condition.S3Prefix.ToKey(), "photos/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	func2, err := condition.NewStringEqualsFunc("", condition.S3Prefix.ToKey(), "docs/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		statement1 Statement
+		statement2 Statement
+	}{
+		// Overlapping action patterns which do not contain each other.
+		{
+			NewStatement("", Allow, NewActionSet("s3:Get*"), NewResourceSet(NewResource("mybucket/*")), condition.NewFunctions()),
+			NewStatement("", Allow, NewActionSet("s3:*Object"), NewResourceSet(NewResource("mybucket/*")), condition.NewFunctions()),
+		},
+		// Overlapping resource patterns which do not contain each other.
+		{
+			NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource("mybucket/a?")), condition.NewFunctions()),
+			NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource("mybucket/?b")), condition.NewFunctions()),
+		},
+		// Policy variable is not contained by '?' matching its literal text.
+		{
+			NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource("mybucket/???????????????")), condition.NewFunctions()),
+			NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource("mybucket/${aws:username}")), condition.NewFunctions()),
+		},
+		// Same condition key with different values.
+		{
+			NewStatement("", Allow, NewActionSet(ListBucketAction), NewResourceSet(NewResource("mybucket")), condition.NewFunctions(func1)),
+			NewStatement("", Allow, NewActionSet(ListBucketAction), NewResourceSet(NewResource("mybucket")), condition.NewFunctions(func2)),
+		},
+	}
+
+	for i, testCase := range testCases {
+		policy1 := Policy{Version: DefaultVersion, Statements: []Statement{testCase.statement1}}
+		policy2 := Policy{Version: DefaultVersion, Statements: []Statement{testCase.statement2}}
+
+		if result := policy1.Intersect(policy2); len(result.Statements) != 0 {
+			t.Fatalf("case %v: expected no statements, got: %v", i+1, result.Statements)
+		}
+	}
+}
+
+func TestPolicyIntersectPolicyVariables(t *testing.T) {
+	userPolicy := Policy{Version: DefaultVersion, Statements: []Statement{
+		NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource("mybucket/${aws:username}")), condition.NewFunctions()),
+	}}
+
+	testCases := []struct {
+		resource       string
+		objectName     string
+		expectedResult bool
+	}{
+		// Fifteen '?' match the literal variable but not "bob".
+		{"mybucket/???????????????", "bob", false},
+		{"mybucket/*", "bob", true},
+		{"mybucket/*", "alice", false},
+		{"mybucket/${aws:username}", "bob", true},
+		{"mybucket/a*", "bob", false},
+	}
+
+	for i, testCase := range testCases {
+		policy := Policy{Version: DefaultVersion, Statements: []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource(testCase.resource)), condition.NewFunctions()),
+		}}
+		args := Args{
+			Action:          GetObjectAction,
+			BucketName:      "mybucket",
+			ObjectName:      testCase.objectName,
+			ConditionValues: map[string][]string{"username": {"bob"}},
+		}
+
+		result := policy.Intersect(userPolicy).IsAllowed(args)
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+		if result && (!policy.IsAllowed(args) || !userPolicy.IsAllowed(args)) {
+			t.Fatalf("case %v: intersection allows more than both policies", i+1)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"fmt"
+)
+
+// LayeredPolicy - policy layered over a base policy, e.g. a user policy over
+// a default policy.
+type LayeredPolicy struct {
+	Base     Policy
+	Override Policy
+}
+
+// Layered - returns override layered over base.
+func Layered(base, override Policy) LayeredPolicy {
+	return LayeredPolicy{Base: base, Override: override}
+}
+
+// Explain - evaluates args against both layers, each the same way as
+// Policy.Explain, and explains the decision. Precedence is:
+//  1. an explicit Deny of override, then of base, denies the request;
+//  2. otherwise an Allow of override, then of base, allows the request;
+//  3. otherwise the request is implicitly denied.
+//
+// Hence override can extend what base allows but can not lift a Deny of
+// base. The reason of the deciding layer is prefixed by "override policy: "
+// or "base policy: ", and StatementIndex and SID refer to its statement.
+func (lp LayeredPolicy) Explain(args Args) Explanation {
+	override := lp.Override.Explain(args)
+	if override.Decision == DecisionExplicitDeny {
+		return layerExplanation("override", override)
+	}
+
+	base := lp.Base.Explain(args)
+	if base.Decision == DecisionExplicitDeny {
+		return layerExplanation("base", base)
+	}
+
+	if override.Decision == DecisionAllow {
+		return layerExplanation("override", override)
+	}
+	if base.Decision == DecisionAllow {
+		return layerExplanation("base", base)
+	}
+
+	return Explanation{
+		Decision:       DecisionImplicitDeny,
+		StatementIndex: -1,
+		Reason:         fmt.Sprintf("no statement of either policy allows action '%s'", args.Action),
+	}
+}
+
+func layerExplanation(layer string, explanation Explanation) Explanation {
+	explanation.Reason = fmt.Sprintf("%s policy: %s", layer, explanation.Reason)
+	return explanation
+}
+
+// Decision - returns the decision of Explain.
+func (lp LayeredPolicy) Decision(args Args) Decision {
+	return lp.Explain(args).Decision
+}
+
+// IsAllowed - checks whether args are allowed as per Explain.
+func (lp LayeredPolicy) IsAllowed(args Args) bool {
+	return lp.Decision(args).IsAllowed()
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLayeredPolicyExplain(t *testing.T) {
+	parse := func(doc string) Policy {
+		p, err := ParseConfig(strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return *p
+	}
+
+	base := parse(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Sid": "BaseRead", "Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"},
+        {"Sid": "BaseDenySecrets", "Effect": "Deny", "Action": "s3:*", "Resource": "arn:aws:s3:::mybucket/secrets/*"}
+    ]
+}`)
+	override := parse(`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {"Sid": "UserWrite", "Effect": "Allow", "Action": ["s3:PutObject", "s3:GetObject"], "Resource": ["arn:aws:s3:::mybucket/*", "arn:aws:s3:::yourbucket/*"]},
+        {"Sid": "UserDenyLogs", "Effect": "Deny", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/logs/*"}
+    ]
+}`)
+	lp := Layered(base, override)
+
+	object := func(action Action, bucket, object string) Args {
+		return Args{Action: action, BucketName: bucket, ObjectName: object}
+	}
+
+	testCases := []struct {
+		args             Args
+		expectedDecision Decision
+		expectedSID      ID
+		expectedPrefix   string
+	}{
+		// Override allow extends base.
+		{object(PutObjectAction, "mybucket", "a.txt"), DecisionAllow, "UserWrite", "override policy: "},
+		{object(GetObjectAction, "yourbucket", "a.txt"), DecisionAllow, "UserWrite", "override policy: "},
+		{object(GetObjectAction, "mybucket", "a.txt"), DecisionAllow, "UserWrite", "override policy: "},
+		// Base deny still blocks.
+		{object(PutObjectAction, "mybucket", "secrets/key"), DecisionExplicitDeny, "BaseDenySecrets", "base policy: "},
+		{object(GetObjectAction, "mybucket", "secrets/key"), DecisionExplicitDeny, "BaseDenySecrets", "base policy: "},
+		// Override deny blocks base allow.
+		{object(GetObjectAction, "mybucket", "logs/a.log"), DecisionExplicitDeny, "UserDenyLogs", "override policy: "},
+		{object(DeleteObjectAction, "mybucket", "a.txt"), DecisionImplicitDeny, "", "no statement"},
+	}
+
+	for i, testCase := range testCases {
+		explanation := lp.Explain(testCase.args)
+
+		if explanation.Decision != testCase.expectedDecision {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedDecision, explanation.Decision)
+		}
+		if explanation.SID != testCase.expectedSID {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedSID, explanation.SID)
+		}
+		if !strings.HasPrefix(explanation.Reason, testCase.expectedPrefix) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedPrefix, explanation.Reason)
+		}
+		if lp.IsAllowed(testCase.args) != (testCase.expectedDecision == DecisionAllow) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedDecision == DecisionAllow, lp.IsAllowed(testCase.args))
+		}
+	}
+
+	// Base alone decides under an empty override.
+	lp = Layered(base, Policy{Version: DefaultVersion})
+	if decision := lp.Decision(object(GetObjectAction, "mybucket", "a.txt")); decision != DecisionAllow {
+		t.Fatalf("expected: %v, got: %v", DecisionAllow, decision)
+	}
+	if decision := lp.Decision(object(PutObjectAction, "mybucket", "a.txt")); decision != DecisionImplicitDeny {
+		t.Fatalf("expected: %v, got: %v", DecisionImplicitDeny, decision)
+	}
+}
@@ -18,11 +18,14 @@
 package policy
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"strings"
 
 	"github.com/trinet2005/oss-go-sdk/pkg/set"
+	"github.com/trinet2005/oss-pkg/policy/condition"
 )
 
 // DefaultVersion - default policy version as per AWS S3 specification.
@@ -39,6 +42,31 @@ type Args struct {
 	ObjectName      string                 `json:"object"`
 	Claims          map[string]interface{} `json:"claims"`
 	DenyOnly        bool                   `json:"denyOnly"` // only applies deny
+	// Anonymous marks an unauthenticated request. Condition values of
+	// identity keys, see condition.IdentityKeys, are ignored and
+	// "aws:principaltype" is "Anonymous" unless given. Allow statements
+	// referring to identity keys, in conditions or as policy variables of
+	// resources, never allow anonymous requests, while Deny statements
+	// are evaluated without identity values, e.g. a Deny of requests whose
+	// "aws:username" is not "alice" applies.
+	Anonymous bool `json:"anonymous"`
+}
+
+// anonymousArgs - returns args of an anonymous request without condition
+// values of identity keys.
+func anonymousArgs(args Args) Args {
+	values := make(map[string][]string, len(args.ConditionValues)+1)
+	for k, v := range args.ConditionValues {
+		values[k] = v
+	}
+	for _, key := range condition.IdentityKeys {
+		delete(values, key.Name())
+	}
+	if _, ok := values[condition.AWSPrincipalType.Name()]; !ok {
+		values[condition.AWSPrincipalType.Name()] = []string{"Anonymous"}
+	}
+	args.ConditionValues = values
+	return args
 }
 
 // GetValuesFromClaims returns the list of values for the input claimName.
@@ -168,13 +196,21 @@ func (iamp Policy) IsAllowedActions(bucketName, objectName string, conditionValu
 	return actionSet
 }
 
-// IsAllowed - checks given policy args is allowed to continue the Rest API.
-func (iamp Policy) IsAllowed(args Args) bool {
+// evaluate - evaluates args against the statements and returns the decision
+// with the index of the deciding statement, -1 when no statement decided. A
+// non-nil ctx is checked before evaluating each statement, its error ends
+// the evaluation.
+func (iamp Policy) evaluate(ctx context.Context, args Args) (Decision, int, error) {
 	// Check all deny statements. If any one statement denies, return false.
-	for _, statement := range iamp.Statements {
+	for i, statement := range iamp.Statements {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return DecisionImplicitDeny, -1, err
+			}
+		}
 		if statement.Effect == Deny {
 			if !statement.IsAllowed(args) {
-				return false
+				return DecisionExplicitDeny, i, nil
 			}
 		}
 	}
@@ -185,24 +221,48 @@ func (iamp Policy) IsAllowed(args Args) bool {
 	// specific scenarios where we only want to validate
 	// 'Deny' only policies.
 	if args.DenyOnly {
-		return true
+		return DecisionAllow, -1, nil
 	}
 
 	// For owner, its allowed by default.
 	if args.IsOwner {
-		return true
+		return DecisionAllow, -1, nil
 	}
 
 	// Check all allow statements. If any one statement allows, return true.
-	for _, statement := range iamp.Statements {
+	for i, statement := range iamp.Statements {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return DecisionImplicitDeny, -1, err
+			}
+		}
 		if statement.Effect == Allow {
 			if statement.IsAllowed(args) {
-				return true
+				return DecisionAllow, i, nil
 			}
 		}
 	}
 
-	return false
+	return DecisionImplicitDeny, -1, nil
+}
+
+// IsAllowed - checks given policy args is allowed to continue the Rest API.
+func (iamp Policy) IsAllowed(args Args) bool {
+	decision, _, _ := iamp.evaluate(nil, args)
+	return decision.IsAllowed()
+}
+
+// IsAllowedCtx - checks given policy args is allowed like IsAllowed, but
+// checks ctx before evaluating each statement and returns the context error
+// once ctx is done. This bounds the evaluation of policies with thousands of
+// statements, normal policies evaluate far quicker than any practical
+// deadline.
+func (iamp Policy) IsAllowedCtx(ctx context.Context, args Args) (bool, error) {
+	decision, _, err := iamp.evaluate(ctx, args)
+	if err != nil {
+		return false, err
+	}
+	return decision.IsAllowed(), nil
 }
 
 // IsEmpty - returns whether policy is empty or not.
@@ -216,14 +276,150 @@ func (iamp Policy) isValid() error {
 		return Errorf("invalid version '%v'", iamp.Version)
 	}
 
+	sids := make(map[ID]struct{})
 	for _, statement := range iamp.Statements {
 		if err := statement.isValid(); err != nil {
 			return err
 		}
+
+		// Sid is optional, but when present it must be unique
+		// within the policy.
+		if statement.SID == "" {
+			continue
+		}
+		if _, ok := sids[statement.SID]; ok {
+			return Errorf("duplicate Sid '%v' found", statement.SID)
+		}
+		sids[statement.SID] = struct{}{}
 	}
 	return nil
 }
 
+// StatementBySid returns the statement with the given Sid, if any.
+func (iamp Policy) StatementBySid(sid string) (Statement, bool) {
+	if sid == "" {
+		return Statement{}, false
+	}
+	for _, statement := range iamp.Statements {
+		if statement.SID == ID(sid) {
+			return statement, true
+		}
+	}
+	return Statement{}, false
+}
+
+// WildcardBucketKey - key of SplitByBucket for statements not limited to
+// a single bucket.
+const WildcardBucketKey = "*"
+
+// SplitByBucket - groups statements by the bucket their resources refer to,
+// e.g. "mybucket" for "arn:aws:s3:::mybucket/*". A statement referring to
+// several buckets is copied into the policy of each bucket with only the
+// resources of that bucket. Resources whose bucket portion contains a
+// wildcard or a policy variable, e.g. "*" or "${aws:username}/*", as well
+// as control-plane resources belong to WildcardBucketKey, which also holds
+// statements without resources, i.e. admin and KMS statements. As the
+// policy of a bucket does not include WildcardBucketKey, enforcement points
+// should evaluate both.
+func (iamp Policy) SplitByBucket() map[string]Policy {
+	policies := map[string]Policy{}
+	add := func(bucket string, statement Statement) {
+		p, ok := policies[bucket]
+		if !ok {
+			p = Policy{ID: iamp.ID, Version: iamp.Version}
+		}
+		p.Statements = append(p.Statements, statement)
+		policies[bucket] = p
+	}
+
+	for _, statement := range iamp.Statements {
+		if len(statement.Resources) == 0 {
+			add(WildcardBucketKey, statement.Clone())
+			continue
+		}
+
+		buckets := []string{}
+		resources := map[string]ResourceSet{}
+		for _, resource := range statement.Resources.sortedResources() {
+			bucket := resource.EnclosingBucketResource().Pattern
+			if resource.isControlPlane() || strings.ContainsAny(bucket, "*?") || hasPolicyVariable(bucket) {
+				bucket = WildcardBucketKey
+			}
+			if _, ok := resources[bucket]; !ok {
+				buckets = append(buckets, bucket)
+				resources[bucket] = NewResourceSet()
+			}
+			resources[bucket].Add(resource)
+		}
+
+		for _, bucket := range buckets {
+			st := statement.Clone()
+			st.Resources = resources[bucket]
+			add(bucket, st)
+		}
+	}
+
+	return policies
+}
+
+// ResourcePaths - returns the sorted, distinct object resources of all
+// statements as OpenAPI style path patterns for documentation, e.g.
+// "/{bucket}/logs/*" for "arn:aws:s3:::*/logs/*". A bucket containing a
+// wildcard becomes the "{bucket}" path parameter, a policy variable becomes
+// a path parameter named after its key, e.g. "{username}" for
+// "${aws:username}", and wildcards of the object portion are kept as is.
+// The "*" resource, matching all buckets and objects, becomes "/{bucket}/*".
+// Other bucket and control-plane resources are not included.
+func (iamp Policy) ResourcePaths() []string {
+	paths := set.NewStringSet()
+	for _, statement := range iamp.Statements {
+		for resource := range statement.Resources {
+			if resource.Pattern == "*" {
+				paths.Add(resourcePath("*/*"))
+			} else if resource.Kind() == ObjectResourceKind {
+				paths.Add(resourcePath(resource.Pattern))
+			}
+		}
+	}
+
+	return paths.ToSlice()
+}
+
+// resourcePath - returns the path pattern of an object resource pattern.
+func resourcePath(pattern string) string {
+	tokens := TokenizeResource(pattern)
+
+	n := 0
+	for n < len(tokens) && tokens[n].Kind != SeparatorTokenKind {
+		n++
+	}
+	bucket, object := tokens[:n], tokens[n:]
+	for _, token := range bucket {
+		if token.Kind == WildcardTokenKind {
+			bucket = []Token{{Kind: VariableTokenKind, Value: "${bucket}"}}
+			break
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("/")
+	for _, tokens := range [][]Token{bucket, object} {
+		for _, token := range tokens {
+			if token.Kind != VariableTokenKind {
+				sb.WriteString(token.Value)
+				continue
+			}
+			name := strings.TrimSuffix(strings.TrimPrefix(token.Value, "${"), "}")
+			if i := strings.IndexByte(name, ':'); i >= 0 {
+				name = name[i+1:]
+			}
+			sb.WriteString("{" + name + "}")
+		}
+	}
+
+	return sb.String()
+}
+
 // MergePolicies merges all the given policies into a single policy dropping any
 // duplicate statements.
 func MergePolicies(inputs ...Policy) Policy {
@@ -290,6 +486,52 @@ func (iamp Policy) Validate() error {
 	return iamp.isValid()
 }
 
+// Limits - limits of a policy checked by ValidateLimits, zero values are
+// not checked.
+type Limits struct {
+	// MaxSize is the maximum size in bytes of the policy marshalled as
+	// JSON, i.e. without whitespace.
+	MaxSize int
+	// MaxStatements is the maximum number of statements.
+	MaxStatements int
+	// MaxResources is the maximum number of resources of all statements.
+	MaxResources int
+}
+
+// AWSManagedPolicyLimits - limits of AWS IAM managed policies, whose size
+// must not exceed 6144 characters without whitespace. AWS does not limit
+// statements and resources other than by size.
+var AWSManagedPolicyLimits = Limits{MaxSize: 6144}
+
+// ValidateLimits - checks the policy against given limits.
+func (iamp Policy) ValidateLimits(limits Limits) error {
+	if limits.MaxStatements > 0 && len(iamp.Statements) > limits.MaxStatements {
+		return Errorf("policy has %d statements, exceeding the limit of %d", len(iamp.Statements), limits.MaxStatements)
+	}
+
+	if limits.MaxResources > 0 {
+		resources := 0
+		for _, statement := range iamp.Statements {
+			resources += len(statement.Resources)
+		}
+		if resources > limits.MaxResources {
+			return Errorf("policy has %d resources, exceeding the limit of %d", resources, limits.MaxResources)
+		}
+	}
+
+	if limits.MaxSize > 0 {
+		data, err := json.Marshal(iamp)
+		if err != nil {
+			return Errorf("%w", err)
+		}
+		if len(data) > limits.MaxSize {
+			return Errorf("policy size of %d bytes exceeds the limit of %d bytes", len(data), limits.MaxSize)
+		}
+	}
+
+	return nil
+}
+
 // ParseConfig - parses data in given reader to Iamp.
 func ParseConfig(reader io.Reader) (*Policy, error) {
 	var iamp Policy
@@ -303,6 +545,83 @@ func ParseConfig(reader io.Reader) (*Policy, error) {
 	return &iamp, iamp.Validate()
 }
 
+// ParseOptions - options to alter policy parsing, the zero value parses the
+// same as ParseConfig.
+type ParseOptions struct {
+	// LenientARNPrefix accepts resource ARNs whose "arn:aws:s3:" prefix is
+	// in any case, e.g. "ARN:AWS:S3:::mybucket", and normalizes the prefix
+	// to lower case. AWS treats the prefix case sensitively.
+	LenientARNPrefix bool
+}
+
+// ParseConfigWithOptions - parses data in given reader to Iamp as per given
+// options.
+func ParseConfigWithOptions(reader io.Reader, opts ParseOptions) (*Policy, error) {
+	if !opts.LenientARNPrefix {
+		return ParseConfig(reader)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, Errorf("%w", err)
+	}
+
+	if data, err = normalizeARNPrefixes(data); err != nil {
+		return nil, Errorf("%w", err)
+	}
+
+	return ParseConfig(bytes.NewReader(data))
+}
+
+// normalizeARNPrefixes - lower cases the "arn:aws:s3:" prefix of resources
+// of all statements in policy data.
+func normalizeARNPrefixes(data []byte) ([]byte, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	m, ok := doc.(map[string]interface{})
+	if !ok {
+		return data, nil
+	}
+
+	statements, ok := m["Statement"].([]interface{})
+	if !ok {
+		return data, nil
+	}
+
+	for _, statement := range statements {
+		st, ok := statement.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		switch resources := st["Resource"].(type) {
+		case string:
+			st["Resource"] = normalizeARNPrefix(resources)
+		case []interface{}:
+			for i, resource := range resources {
+				if s, ok := resource.(string); ok {
+					resources[i] = normalizeARNPrefix(s)
+				}
+			}
+		}
+	}
+
+	return json.Marshal(doc)
+}
+
+// normalizeARNPrefix - lower cases the "arn:aws:s3:" prefix of s, if any.
+func normalizeARNPrefix(s string) string {
+	if len(s) >= len(resourceARNServicePrefix) && strings.EqualFold(s[:len(resourceARNServicePrefix)], resourceARNServicePrefix) {
+		return resourceARNServicePrefix + s[len(resourceARNServicePrefix):]
+	}
+	return s
+}
+
 // Equals returns true if the two policies are identical
 func (iamp *Policy) Equals(p Policy) bool {
 	if iamp.ID != p.ID || iamp.Version != p.Version {
@@ -19,8 +19,12 @@ package policy
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -1551,3 +1555,787 @@ func TestMergePolicies(t *testing.T) {
 		}
 	}
 }
+
+func TestPolicyDuplicateSid(t *testing.T) {
+	testCases := []struct {
+		data      string
+		expectErr bool
+	}{
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Sid": "statement1",
+            "Effect": "Allow",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        },
+        {
+            "Sid": "statement1",
+            "Effect": "Deny",
+            "Action": "s3:PutObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        }
+    ]
+}`, true},
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Sid": "statement1",
+            "Effect": "Allow",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        },
+        {
+            "Sid": "statement2",
+            "Effect": "Deny",
+            "Action": "s3:PutObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        }
+    ]
+}`, false},
+		// Statements without Sid are not considered duplicates.
+		{`{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        },
+        {
+            "Effect": "Deny",
+            "Action": "s3:PutObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        }
+    ]
+}`, false},
+	}
+
+	for i, testCase := range testCases {
+		_, err := ParseConfig(strings.NewReader(testCase.data))
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+	}
+}
+
+func TestPolicyStatementBySid(t *testing.T) {
+	p := Policy{
+		Version: DefaultVersion,
+		Statements: []Statement{
+			NewStatement(
+				"statement1",
+				Allow,
+				NewActionSet(GetObjectAction),
+				NewResourceSet(NewResource("mybucket/*")),
+				condition.NewFunctions(),
+			),
+			NewStatement(
+				"",
+				Allow,
+				NewActionSet(GetBucketLocationAction),
+				NewResourceSet(NewResource("mybucket")),
+				condition.NewFunctions(),
+			),
+			NewStatement(
+				"statement3",
+				Deny,
+				NewActionSet(PutObjectAction),
+				NewResourceSet(NewResource("mybucket/*")),
+				condition.NewFunctions(),
+			),
+		},
+	}
+
+	testCases := []struct {
+		sid            string
+		expectedFound  bool
+		expectedEffect Effect
+	}{
+		{"statement1", true, Allow},
+		{"statement3", true, Deny},
+		{"statement2", false, ""},
+		{"", false, ""},
+	}
+
+	for i, testCase := range testCases {
+		st, found := p.StatementBySid(testCase.sid)
+		if found != testCase.expectedFound {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedFound, found)
+		}
+		if st.Effect != testCase.expectedEffect {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedEffect, st.Effect)
+		}
+	}
+}
+
+func TestPolicyPresignedSignatureAge(t *testing.T) {
+	data := `{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*"
+        },
+        {
+            "Effect": "Deny",
+            "Action": "s3:GetObject",
+            "Resource": "arn:aws:s3:::mybucket/*",
+            "Condition": {
+                "StringEquals": {
+                    "s3:authType": "REST-QUERY-STRING"
+                },
+                "NumericGreaterThan": {
+                    "s3:signatureAge": "600000"
+                }
+            }
+        }
+    ]
+}`
+	p, err := ParseConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		conditionValues map[string][]string
+		expectedResult  bool
+	}{
+		// Presigned URL within allowed lifetime.
+		{map[string][]string{"authType": {"REST-QUERY-STRING"}, "signatureAge": {"300000"}}, true},
+		// Presigned URL exceeding allowed lifetime.
+		{map[string][]string{"authType": {"REST-QUERY-STRING"}, "signatureAge": {"3600000"}}, false},
+		// Header signed requests are not restricted.
+		{map[string][]string{"authType": {"REST-HEADER"}, "signatureAge": {"3600000"}}, true},
+	}
+
+	for i, testCase := range testCases {
+		result := p.IsAllowed(Args{
+			AccountName:     "Q3AM3UQ867SPQQA43P2F",
+			Action:          GetObjectAction,
+			BucketName:      "mybucket",
+			ObjectName:      "myobject",
+			ConditionValues: testCase.conditionValues,
+		})
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+// budgetContext - context which is cancelled after its Err method has been
+// called budget times.
+type budgetContext struct {
+	context.Context
+	budget int
+}
+
+func (ctx *budgetContext) Err() error {
+	if ctx.budget <= 0 {
+		return context.Canceled
+	}
+	ctx.budget--
+	return nil
+}
+
+func TestPolicyIsAllowedCtx(t *testing.T) {
+	var statements []Statement
+	for i := 0; i < 1000; i++ {
+		statements = append(statements, NewStatement("",
+			Allow,
+			NewActionSet(GetObjectAction),
+			NewResourceSet(NewResource(fmt.Sprintf("mybucket%v/*", i))),
+			condition.NewFunctions(),
+		))
+	}
+	statements = append(statements, NewStatement("",
+		Deny,
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket0/secret/*")),
+		condition.NewFunctions(),
+	))
+	policy := Policy{Version: DefaultVersion, Statements: statements}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	testCases := []struct {
+		ctx            context.Context
+		args           Args
+		expectedResult bool
+		expectedErr    error
+	}{
+		{context.Background(), Args{Action: GetObjectAction, BucketName: "mybucket999", ObjectName: "myobject"}, true, nil},
+		{context.Background(), Args{Action: GetObjectAction, BucketName: "mybucket1000", ObjectName: "myobject"}, false, nil},
+		{context.Background(), Args{Action: GetObjectAction, BucketName: "mybucket0", ObjectName: "secret/myobject"}, false, nil},
+		{context.Background(), Args{Action: GetObjectAction, BucketName: "mybucket1000", ObjectName: "myobject", IsOwner: true}, true, nil},
+		{cancelled, Args{Action: GetObjectAction, BucketName: "mybucket0", ObjectName: "myobject"}, false, context.Canceled},
+		// Cancelled while evaluating deny statements.
+		{&budgetContext{context.Background(), 500}, Args{Action: GetObjectAction, BucketName: "mybucket999", ObjectName: "myobject"}, false, context.Canceled},
+		// Cancelled while evaluating allow statements.
+		{&budgetContext{context.Background(), 1500}, Args{Action: GetObjectAction, BucketName: "mybucket999", ObjectName: "myobject"}, false, context.Canceled},
+		// Allowed before the budget is exhausted.
+		{&budgetContext{context.Background(), 1500}, Args{Action: GetObjectAction, BucketName: "mybucket10", ObjectName: "myobject"}, true, nil},
+	}
+
+	for i, testCase := range testCases {
+		result, err := policy.IsAllowedCtx(testCase.ctx, testCase.args)
+
+		if !errors.Is(err, testCase.expectedErr) {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectedErr, err)
+		}
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+
+		if err == nil && result != policy.IsAllowed(testCase.args) {
+			t.Fatalf("case %v: expected IsAllowedCtx to agree with IsAllowed", i+1)
+		}
+	}
+}
+
+func TestAllowAllDenyAll(t *testing.T) {
+	testCases := []Args{
+		{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject"},
+		{Action: PutObjectAction, BucketName: "anybucket", ObjectName: "a/b/c"},
+		{Action: ListAllMyBucketsAction},
+		{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: map[string][]string{"prefix": {"home/"}}},
+		{Action: DeleteBucketAction, BucketName: "mybucket", IsOwner: true},
+		{Action: ServerInfoAdminAction},
+		{Action: KMSCreateKeyAction},
+	}
+
+	for i, args := range testCases {
+		if !AllowAll().IsAllowed(args) {
+			t.Fatalf("case %v: expected AllowAll to allow %v", i+1, args.Action)
+		}
+		if DenyAll().IsAllowed(args) {
+			t.Fatalf("case %v: expected DenyAll to deny %v", i+1, args.Action)
+		}
+		if MergePolicies(AllowAll(), DenyAll()).IsAllowed(args) {
+			t.Fatalf("case %v: expected DenyAll to override AllowAll for %v", i+1, args.Action)
+		}
+	}
+
+	for i, p := range []Policy{AllowAll(), DenyAll(), NewAllowPolicy([]string{"s3:GetObject", "s3:ListBucket"}, []string{"mybucket", "mybucket/*"})} {
+		if err := p.Validate(); err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		data, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		parsed, err := ParseConfig(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		if !parsed.Equals(p) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, p, parsed)
+		}
+	}
+}
+
+func TestNewAllowPolicy(t *testing.T) {
+	p := NewAllowPolicy([]string{"s3:GetObject", "s3:ListBucket"}, []string{"mybucket", "mybucket/*"})
+
+	testCases := []struct {
+		args           Args
+		expectedResult bool
+	}{
+		{Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, true},
+		{Args{Action: ListBucketAction, BucketName: "mybucket"}, true},
+		{Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, false},
+		{Args{Action: GetObjectAction, BucketName: "yourbucket", ObjectName: "myobject"}, false},
+	}
+
+	for i, testCase := range testCases {
+		result := p.IsAllowed(testCase.args)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+
+	if err := NewAllowPolicy([]string{"s3:Unknown"}, []string{"mybucket/*"}).Validate(); err == nil {
+		t.Fatalf("expected unknown action to fail validation")
+	}
+	if err := NewAllowPolicy([]string{"s3:GetObject"}, nil).Validate(); err == nil {
+		t.Fatalf("expected missing resources to fail validation")
+	}
+}
+
+func TestParseConfigWithOptions(t *testing.T) {
+	data := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:ListBucket"],
+      "Resource": ["ARN:AWS:S3:::mybucket", "Arn:Aws:S3:::mybucket/*"],
+      "Condition": {"NumericLessThanEquals": {"s3:max-keys": 9007199254740993}}
+    },
+    {
+      "Effect": "Allow",
+      "Action": "s3:PutObject",
+      "Resource": "ARN:aws:s3:::mybucket/uploads/*"
+    }
+  ]
+}`
+
+	testCases := []struct {
+		data      string
+		opts      ParseOptions
+		expectErr bool
+	}{
+		{data, ParseOptions{}, true},
+		{data, ParseOptions{LenientARNPrefix: true}, false},
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"}]}`, ParseOptions{}, false},
+		{`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "mybucket/*"}]}`, ParseOptions{LenientARNPrefix: true}, true},
+		{`[]`, ParseOptions{LenientARNPrefix: true}, true},
+		{`{`, ParseOptions{LenientARNPrefix: true}, true},
+	}
+
+	for i, testCase := range testCases {
+		result, err := ParseConfigWithOptions(strings.NewReader(testCase.data), testCase.opts)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v, err: %v", i+1, testCase.expectErr, expectErr, err)
+		}
+
+		if !testCase.expectErr && result == nil {
+			t.Fatalf("case %v: expected policy", i+1)
+		}
+	}
+
+	p, err := ParseConfigWithOptions(strings.NewReader(data), ParseOptions{LenientARNPrefix: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedResources := NewResourceSet(NewResource("mybucket"), NewResource("mybucket/*"))
+	if !p.Statements[0].Resources.Equals(expectedResources) {
+		t.Fatalf("expected: %v, got: %v", expectedResources, p.Statements[0].Resources)
+	}
+	if !p.IsAllowed(Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "uploads/a.txt"}) {
+		t.Fatalf("expected normalized resource to match")
+	}
+
+	// Numbers are preserved exactly.
+	if !p.IsAllowed(Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: map[string][]string{"max-keys": {"9007199254740993"}}}) {
+		t.Fatalf("expected condition number to be preserved")
+	}
+}
+
+func TestPolicySplitByBucket(t *testing.T) {
+	p, err := ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "ReadBoth",
+      "Effect": "Allow",
+      "Action": ["s3:GetObject", "s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::mybucket", "arn:aws:s3:::mybucket/*", "arn:aws:s3:::yourbucket/public/*"]
+    },
+    {
+      "Sid": "DenyPrivate",
+      "Effect": "Deny",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::mybucket/private/*"]
+    },
+    {
+      "Sid": "ListAll",
+      "Effect": "Allow",
+      "Action": ["s3:ListAllMyBuckets"],
+      "Resource": ["arn:aws:s3:::*"]
+    },
+    {
+      "Sid": "Home",
+      "Effect": "Allow",
+      "Action": ["s3:PutObject"],
+      "Resource": ["arn:aws:s3:::logs*/*", "arn:aws:s3:::${aws:username}/*", "arn:aws:s3:::yourbucket/uploads/*"]
+    },
+    {
+      "Sid": "Admin",
+      "Effect": "Allow",
+      "Action": ["admin:ServerInfo"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedResult := map[string][]Statement{
+		"mybucket": {
+			NewStatement("ReadBoth", Allow, NewActionSet(GetObjectAction, ListBucketAction),
+				NewResourceSet(NewResource("mybucket"), NewResource("mybucket/*")), condition.NewFunctions()),
+			NewStatement("DenyPrivate", Deny, NewActionSet(GetObjectAction),
+				NewResourceSet(NewResource("mybucket/private/*")), condition.NewFunctions()),
+		},
+		"yourbucket": {
+			NewStatement("ReadBoth", Allow, NewActionSet(GetObjectAction, ListBucketAction),
+				NewResourceSet(NewResource("yourbucket/public/*")), condition.NewFunctions()),
+			NewStatement("Home", Allow, NewActionSet(PutObjectAction),
+				NewResourceSet(NewResource("yourbucket/uploads/*")), condition.NewFunctions()),
+		},
+		WildcardBucketKey: {
+			NewStatement("ListAll", Allow, NewActionSet(ListAllMyBucketsAction),
+				NewResourceSet(NewResource("*")), condition.NewFunctions()),
+			NewStatement("Home", Allow, NewActionSet(PutObjectAction),
+				NewResourceSet(NewResource("logs*/*"), NewResource("${aws:username}/*")), condition.NewFunctions()),
+			NewStatement("Admin", Allow, NewActionSet(ServerInfoAdminAction),
+				NewResourceSet(), condition.NewFunctions()),
+		},
+	}
+
+	result := p.SplitByBucket()
+	if len(result) != len(expectedResult) {
+		t.Fatalf("expected: %v buckets, got: %v", len(expectedResult), len(result))
+	}
+
+	for bucket, statements := range expectedResult {
+		expected := Policy{Version: DefaultVersion, Statements: statements}
+		got, ok := result[bucket]
+		if !ok {
+			t.Fatalf("bucket %v: expected policy", bucket)
+		}
+		if !got.Equals(expected) {
+			t.Fatalf("bucket %v: expected: %v, got: %v", bucket, expected, got)
+		}
+		for i := range statements {
+			if got.Statements[i].SID != statements[i].SID {
+				t.Fatalf("bucket %v: expected: %v, got: %v", bucket, statements[i].SID, got.Statements[i].SID)
+			}
+		}
+		if err := got.Validate(); err != nil {
+			t.Fatalf("bucket %v: unexpected error: %v", bucket, err)
+		}
+	}
+
+	// The original policy is not modified.
+	if len(p.Statements[0].Resources) != 3 {
+		t.Fatalf("expected original statement to keep its resources, got: %v", p.Statements[0].Resources)
+	}
+
+	// A bucket policy together with the wildcard policy allows the same.
+	testCases := []Args{
+		{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "a.txt"},
+		{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "private/a.txt"},
+		{Action: GetObjectAction, BucketName: "yourbucket", ObjectName: "public/a.txt"},
+		{Action: GetObjectAction, BucketName: "yourbucket", ObjectName: "private/a.txt"},
+		{Action: PutObjectAction, BucketName: "yourbucket", ObjectName: "uploads/a.txt"},
+		{Action: PutObjectAction, BucketName: "logs2021", ObjectName: "a.log"},
+	}
+	for i, args := range testCases {
+		bucketPolicy := MergePolicies(result[args.BucketName], result[WildcardBucketKey])
+		if bucketPolicy.IsAllowed(args) != p.IsAllowed(args) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, p.IsAllowed(args), bucketPolicy.IsAllowed(args))
+		}
+	}
+}
+
+func TestPolicyObjectLockLegalHold(t *testing.T) {
+	p, err := ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:PutObjectLegalHold"],
+      "Resource": ["arn:aws:s3:::mybucket/*"],
+      "Condition": {"StringEquals": {"s3:object-lock-legal-hold": "ON"}}
+    },
+    {
+      "Effect": "Allow",
+      "Action": ["s3:PutObject", "s3:GetObjectLegalHold"],
+      "Resource": ["arn:aws:s3:::mybucket/*"]
+    },
+    {
+      "Effect": "Deny",
+      "Action": ["s3:PutObject"],
+      "Resource": ["arn:aws:s3:::mybucket/records/*"],
+      "Condition": {"Bool": {"s3:object-lock-legal-hold": "false"}}
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	legalHold := func(status string) map[string][]string {
+		return map[string][]string{"object-lock-legal-hold": {status}}
+	}
+
+	testCases := []struct {
+		args           Args
+		expectedResult bool
+	}{
+		// Only placing a legal hold is allowed.
+		{Args{Action: PutObjectLegalHoldAction, BucketName: "mybucket", ObjectName: "a.txt", ConditionValues: legalHold("ON")}, true},
+		{Args{Action: PutObjectLegalHoldAction, BucketName: "mybucket", ObjectName: "a.txt", ConditionValues: legalHold("OFF")}, false},
+		{Args{Action: PutObjectLegalHoldAction, BucketName: "mybucket", ObjectName: "a.txt"}, false},
+		{Args{Action: PutObjectLegalHoldAction, BucketName: "yourbucket", ObjectName: "a.txt", ConditionValues: legalHold("ON")}, false},
+		// Records must be uploaded with a legal hold.
+		{Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "records/a.txt", ConditionValues: legalHold("ON")}, true},
+		{Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "records/a.txt", ConditionValues: legalHold("OFF")}, false},
+		{Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "other/a.txt", ConditionValues: legalHold("OFF")}, true},
+		// Object resources match normally.
+		{Args{Action: GetObjectLegalHoldAction, BucketName: "mybucket", ObjectName: "records/a.txt"}, true},
+		{Args{Action: GetObjectLegalHoldAction, BucketName: "yourbucket", ObjectName: "records/a.txt"}, false},
+	}
+
+	for i, testCase := range testCases {
+		result := p.IsAllowed(testCase.args)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+
+	// Legal hold is not a supported condition key of GetObjectLegalHold.
+	_, err = ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObjectLegalHold"],
+      "Resource": ["arn:aws:s3:::mybucket/*"],
+      "Condition": {"StringEquals": {"s3:object-lock-legal-hold": "ON"}}
+    }
+  ]
+}`))
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func FuzzPolicyParse(f *testing.F) {
+	seeds := []string{
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Sid": "A", "Effect": "Deny", "NotAction": ["s3:PutObject"], "Resource": ["arn:aws:s3:::*"]}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:ListBucket"], "Resource": "arn:aws:s3:::mybucket", "Condition": {"StringLike": {"s3:prefix": ["${aws:username}/*"]}}}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:GetObject"], "Resource": "arn:aws:s3:::mybucket/*", "Condition": {"IpAddress": {"aws:SourceIp": "192.168.1.0/24"}, "Bool": {"aws:SecureTransport": "true"}}}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["admin:ServerInfo"]}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": ["s3:*"], "Resource": "arn:aws:s3::111122223333:mybucket/a:b/c"}]}`,
+		`{"Version": "2012-10-17", "Statement": []}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::/*"}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Maybe", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"}]}`,
+		`{"Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::mybucket/*"}]}`,
+		`{"Version": "2012-10-17", "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::ÿ*\u0000"}]}`,
+		`{}`,
+		`[]`,
+		`null`,
+		`{`,
+		"",
+	}
+	for _, seed := range seeds {
+		f.Add([]byte(seed))
+	}
+
+	args := Args{
+		AccountName:     "alice",
+		Action:          GetObjectAction,
+		BucketName:      "mybucket",
+		ObjectName:      "alice/myobject",
+		ConditionValues: map[string][]string{"username": {"alice"}, "prefix": {"alice/"}, "SourceIp": {"192.168.1.10"}},
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		p, err := ParseConfig(bytes.NewReader(data))
+		if err != nil {
+			return
+		}
+
+		allowed := p.IsAllowed(args)
+		p.IsAllowedActions(args.BucketName, args.ObjectName, args.ConditionValues)
+
+		out, err := json.Marshal(p)
+		if err != nil {
+			t.Fatalf("unexpected error marshalling %s: %v", data, err)
+		}
+
+		parsed, err := ParseConfig(bytes.NewReader(out))
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %v", out, err)
+		}
+
+		if !parsed.Equals(*p) {
+			t.Fatalf("expected: %v, got: %v", p, parsed)
+		}
+
+		if parsed.IsAllowed(args) != allowed {
+			t.Fatalf("expected re-parsed policy to allow %v", allowed)
+		}
+	})
+}
+
+func TestPolicyIsAllowedPerUserPrefix(t *testing.T) {
+	p, err := ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::mybucket"],
+      "Condition": {"StringLike": {"s3:prefix": ["home/${aws:username}/*", "public/*"]}}
+    },
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObject"],
+      "Resource": ["arn:aws:s3:::mybucket/home/${aws:username}/*"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	values := func(username, prefix string) map[string][]string {
+		return map[string][]string{"username": {username}, "prefix": {prefix}}
+	}
+
+	testCases := []struct {
+		args           Args
+		expectedResult bool
+	}{
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: values("johndoe", "home/johndoe/docs/")}, true},
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: values("johndoe", "home/janedoe/docs/")}, false},
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: values("janedoe", "home/janedoe/docs/")}, true},
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: values("johndoe", "public/docs/")}, true},
+		// Without a username the variable is compared literally.
+		{Args{Action: ListBucketAction, BucketName: "mybucket", ConditionValues: map[string][]string{"prefix": {"home/johndoe/docs/"}}}, false},
+		{Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "home/johndoe/a.txt", ConditionValues: values("johndoe", "")}, true},
+		{Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "home/janedoe/a.txt", ConditionValues: values("johndoe", "")}, false},
+	}
+
+	for i, testCase := range testCases {
+		result := p.IsAllowed(testCase.args)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestPolicyResourcePaths(t *testing.T) {
+	p, err := ParseConfig(strings.NewReader(`{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Effect": "Allow",
+      "Action": ["s3:GetObject"],
+      "Resource": [
+        "arn:aws:s3:::*/logs/*",
+        "arn:aws:s3:::mybucket/photos/*.jpg",
+        "arn:aws:s3:::mybucket/home/${aws:username}/*",
+        "arn:aws:s3:::logs-??/${jwt:sub}/report-?.csv",
+        "arn:aws:s3:::${aws:username}/*",
+        "arn:aws:s3:::mybucket"
+      ]
+    },
+    {
+      "Effect": "Deny",
+      "Action": ["s3:PutObject"],
+      "Resource": ["arn:aws:s3:::mybucket/photos/*.jpg", "arn:aws:s3:::my*/*"]
+    },
+    {
+      "Effect": "Allow",
+      "Action": ["s3:ListBucket"],
+      "Resource": ["arn:aws:s3:::yourbucket"]
+    }
+  ]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedResult := []string{
+		"/mybucket/home/{username}/*",
+		"/mybucket/photos/*.jpg",
+		"/{bucket}/*",
+		"/{bucket}/logs/*",
+		"/{bucket}/{sub}/report-?.csv",
+		"/{username}/*",
+	}
+
+	result := p.ResourcePaths()
+	if !reflect.DeepEqual(result, expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, result)
+	}
+
+	if result := (Policy{Version: DefaultVersion}).ResourcePaths(); len(result) != 0 {
+		t.Fatalf("expected no paths, got: %v", result)
+	}
+
+	// All resources.
+	all := NewAllowPolicy([]string{"s3:GetObject"}, []string{"*"})
+	expectedResult = []string{"/{bucket}/*"}
+	if result := all.ResourcePaths(); !reflect.DeepEqual(result, expectedResult) {
+		t.Fatalf("expected: %v, got: %v", expectedResult, result)
+	}
+}
+
+func TestPolicyValidateLimits(t *testing.T) {
+	p := NewAllowPolicy([]string{"s3:GetObject"}, []string{"arn:aws:s3:::mybucket/*", "arn:aws:s3:::yourbucket/*"})
+	p.Statements = append(p.Statements, NewStatement("",
+		Deny,
+		NewActionSet(PutObjectAction),
+		NewResourceSet(NewResource("mybucket/secrets/*")),
+		condition.NewFunctions(),
+	))
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	size := len(data)
+
+	var large Policy
+	large.Version = DefaultVersion
+	for i := 0; i < 100; i++ {
+		large.Statements = append(large.Statements, NewStatement(ID(fmt.Sprintf("statement%03d", i)),
+			Allow,
+			NewActionSet(GetObjectAction),
+			NewResourceSet(NewResource(fmt.Sprintf("mybucket/%03d/*", i))),
+			condition.NewFunctions(),
+		))
+	}
+
+	testCases := []struct {
+		policy       Policy
+		limits       Limits
+		expectedErr  bool
+		expectedText string
+	}{
+		{p, Limits{}, false, ""},
+		{p, Limits{MaxSize: size, MaxStatements: 2, MaxResources: 3}, false, ""},
+		{p, Limits{MaxSize: size - 1}, true, "size"},
+		{p, Limits{MaxStatements: 1}, true, "2 statements"},
+		{p, Limits{MaxResources: 2}, true, "3 resources"},
+		{p, AWSManagedPolicyLimits, false, ""},
+		{large, AWSManagedPolicyLimits, true, "exceeds the limit of 6144 bytes"},
+		{Policy{Version: DefaultVersion}, Limits{MaxSize: 100, MaxStatements: 1, MaxResources: 1}, false, ""},
+	}
+
+	for i, testCase := range testCases {
+		err := testCase.policy.ValidateLimits(testCase.limits)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectedErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectedErr, err)
+		}
+		if expectErr && !strings.Contains(err.Error(), testCase.expectedText) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedText, err)
+		}
+	}
+}
@@ -19,14 +19,98 @@ package policy
 
 import (
 	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/trinet2005/oss-go-sdk/pkg/set"
 	"github.com/trinet2005/oss-pkg/wildcard"
 )
 
-// Principal - policy principal.
+// Principal - policy principal. Modify principals by Add and Remove, which
+// keep the index used by Match up to date. Match falls back to matching all
+// patterns once AWS is modified directly to a different size, replacing a
+// principal of AWS directly is not detected.
 type Principal struct {
 	AWS set.StringSet
+
+	// index is built by NewPrincipal, UnmarshalJSON, Add and Remove.
+	index *principalIndex
+}
+
+// principalIndex - groups principal patterns by their literal prefix, i.e.
+// up to the first wildcard, so that only patterns whose literal prefix is
+// a prefix of the principal are matched.
+type principalIndex struct {
+	size     int
+	exact    map[string]struct{}
+	prefixed map[string][]string
+	lengths  []int
+	// other holds invalid UTF-8 patterns, which are always matched.
+	other []string
+}
+
+func newPrincipalIndex(principals set.StringSet) *principalIndex {
+	index := &principalIndex{
+		size:     len(principals),
+		exact:    make(map[string]struct{}),
+		prefixed: make(map[string][]string),
+	}
+	for pattern := range principals {
+		if !utf8.ValidString(pattern) {
+			index.other = append(index.other, pattern)
+			continue
+		}
+
+		i := strings.IndexAny(pattern, "*?")
+		if i < 0 {
+			index.exact[pattern] = struct{}{}
+			continue
+		}
+
+		prefix := pattern[:i]
+		if _, ok := index.prefixed[prefix]; !ok {
+			index.lengths = append(index.lengths, len(prefix))
+		}
+		index.prefixed[prefix] = append(index.prefixed[prefix], pattern)
+	}
+	sort.Ints(index.lengths)
+
+	// Remove duplicate lengths of different prefixes.
+	lengths := index.lengths[:0]
+	for i, l := range index.lengths {
+		if i == 0 || l != index.lengths[i-1] {
+			lengths = append(lengths, l)
+		}
+	}
+	index.lengths = lengths
+
+	return index
+}
+
+func (index *principalIndex) match(principal string) bool {
+	if _, ok := index.exact[principal]; ok {
+		return true
+	}
+
+	for _, l := range index.lengths {
+		if l > len(principal) {
+			break
+		}
+		for _, pattern := range index.prefixed[principal[:l]] {
+			if wildcard.MatchSimple(pattern, principal) {
+				return true
+			}
+		}
+	}
+
+	for _, pattern := range index.other {
+		if wildcard.MatchSimple(pattern, principal) {
+			return true
+		}
+	}
+
+	return false
 }
 
 // IsValid - checks whether Principal is valid or not.
@@ -58,6 +142,10 @@ func (p Principal) MarshalJSON() ([]byte, error) {
 
 // Match - matches given principal is wildcard matching with Principal.
 func (p Principal) Match(principal string) bool {
+	if p.index != nil && p.index.size == len(p.AWS) && utf8.ValidString(principal) {
+		return p.index.match(principal)
+	}
+
 	for _, pattern := range p.AWS.ToSlice() {
 		if wildcard.MatchSimple(pattern, principal) {
 			return true
@@ -87,10 +175,44 @@ func (p *Principal) UnmarshalJSON(data []byte) error {
 	}
 
 	*p = Principal(sp)
+	p.index = newPrincipalIndex(p.AWS)
 
 	return nil
 }
 
+func (p Principal) String() string {
+	return "{" + p.AWS.String() + "}"
+}
+
+// validateNoVariables - checks that no principal uses a policy variable.
+func (p Principal) validateNoVariables() error {
+	for _, principal := range p.AWS.ToSlice() {
+		if hasPolicyVariable(principal) {
+			return Errorf("%w", VariableError{Element: "Principal", Value: principal})
+		}
+	}
+	return nil
+}
+
+// Add - adds principals and updates the index.
+func (p *Principal) Add(principals ...string) {
+	if p.AWS == nil {
+		p.AWS = set.NewStringSet()
+	}
+	for _, principal := range principals {
+		p.AWS.Add(principal)
+	}
+	p.index = newPrincipalIndex(p.AWS)
+}
+
+// Remove - removes principals and updates the index.
+func (p *Principal) Remove(principals ...string) {
+	for _, principal := range principals {
+		p.AWS.Remove(principal)
+	}
+	p.index = newPrincipalIndex(p.AWS)
+}
+
 // Clone clones Principal structure
 func (p Principal) Clone() Principal {
 	return NewPrincipal(p.AWS.ToSlice()...)
@@ -98,5 +220,6 @@ func (p Principal) Clone() Principal {
 
 // NewPrincipal - creates new Principal.
 func NewPrincipal(principals ...string) Principal {
-	return Principal{AWS: set.CreateStringSet(principals...)}
+	aws := set.CreateStringSet(principals...)
+	return Principal{AWS: aws, index: newPrincipalIndex(aws)}
 }
@@ -19,6 +19,7 @@ package policy
 
 import (
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -140,3 +141,88 @@ func TestPrincipalUnmarshalJSON(t *testing.T) {
 		}
 	}
 }
+
+func TestPrincipalMatchIndex(t *testing.T) {
+	principals := []string{
+		"*",
+		"arn:aws:iam::111122223333:root",
+		"arn:aws:iam::111122223333:user/*",
+		"arn:aws:iam::444455556666:user/dev-??",
+		"arn:aws:iam::444455556666:role/*admin*",
+		"arn:aws:iam::7777?8889999:root",
+		"\xffinvalid*",
+	}
+	texts := []string{
+		"",
+		"AccountNumber",
+		"arn:aws:iam::111122223333:root",
+		"arn:aws:iam::111122223333:user/alice",
+		"arn:aws:iam::444455556666:user/dev-01",
+		"arn:aws:iam::444455556666:user/dev-1",
+		"arn:aws:iam::444455556666:user/dev-001",
+		"arn:aws:iam::444455556666:role/superadministrator",
+		"arn:aws:iam::777708889999:root",
+		"arn:aws:iam::7777",
+		"\xfeinvalid-principal",
+		"\xffinvalid-principal",
+	}
+
+	// Compare index matching against linear matching of any subset of
+	// principals of up to two patterns.
+	for i := range principals {
+		for j := i; j < len(principals); j++ {
+			p := NewPrincipal(principals[i], principals[j])
+			linear := Principal{AWS: p.AWS}
+			for _, text := range texts {
+				if expected, got := linear.Match(text), p.Match(text); expected != got {
+					t.Fatalf("%v: %q: expected: %v, got: %v", p, text, expected, got)
+				}
+			}
+		}
+	}
+
+	// Index is not used once principals are modified.
+	p := NewPrincipal("arn:aws:iam::111122223333:root")
+	p.AWS.Add("arn:aws:iam::444455556666:root")
+	if !p.Match("arn:aws:iam::444455556666:root") {
+		t.Fatalf("expected modified principal to match")
+	}
+
+	// Add and Remove update the index, also keeping the size.
+	p = NewPrincipal("alice", "bob")
+	p.Remove("alice")
+	p.Add("carol")
+	if p.Match("alice") {
+		t.Fatalf("expected removed principal not to match")
+	}
+	if !p.Match("carol") {
+		t.Fatalf("expected added principal to match")
+	}
+	if p.index == nil || p.index.size != len(p.AWS) {
+		t.Fatalf("expected index of modified principal to be used")
+	}
+}
+
+func benchmarkPrincipalMatch(b *testing.B, p Principal) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Match("arn:aws:iam::111122223333:user/user-9999")
+	}
+}
+
+func newBenchmarkPrincipal() Principal {
+	var principals []string
+	for i := 0; i < 10000; i++ {
+		principals = append(principals, fmt.Sprintf("arn:aws:iam::%012d:user/*", i))
+	}
+	return NewPrincipal(principals...)
+}
+
+func BenchmarkPrincipalMatch(b *testing.B) {
+	benchmarkPrincipalMatch(b, newBenchmarkPrincipal())
+}
+
+func BenchmarkPrincipalMatchLinear(b *testing.B) {
+	benchmarkPrincipalMatch(b, Principal{AWS: newBenchmarkPrincipal().AWS})
+}
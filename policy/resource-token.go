@@ -0,0 +1,130 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+)
+
+// TokenKind - kind of a resource pattern token.
+type TokenKind int
+
+const (
+	// PrefixTokenKind - ARN prefix, e.g. "arn:aws:s3:::".
+	PrefixTokenKind TokenKind = iota
+	// BucketTokenKind - literal portion of the bucket name.
+	BucketTokenKind
+	// SeparatorTokenKind - '/' separating bucket and path segments.
+	SeparatorTokenKind
+	// LiteralTokenKind - literal portion of an object path segment.
+	LiteralTokenKind
+	// WildcardTokenKind - '*' or '?'.
+	WildcardTokenKind
+	// VariableTokenKind - policy variable, e.g. "${aws:username}".
+	VariableTokenKind
+)
+
+// String - returns string representation of token kind.
+func (kind TokenKind) String() string {
+	switch kind {
+	case PrefixTokenKind:
+		return "prefix"
+	case BucketTokenKind:
+		return "bucket"
+	case SeparatorTokenKind:
+		return "separator"
+	case LiteralTokenKind:
+		return "literal-segment"
+	case WildcardTokenKind:
+		return "wildcard"
+	case VariableTokenKind:
+		return "variable"
+	}
+	return "unknown"
+}
+
+// Token - token of a resource pattern.
+type Token struct {
+	Kind TokenKind
+	// Value is the token as written, e.g. "${aws:username}".
+	Value string
+	// Offset is the byte offset of Value in the tokenized string.
+	Offset int
+}
+
+// TokenizeResource - splits a resource ARN or pattern into tokens for syntax
+// highlighting, e.g. "arn:aws:s3:::my*/home/${aws:username}/*" into the
+// prefix "arn:aws:s3:::", bucket "my", wildcard "*", separator "/", literal
+// "home", separator, variable "${aws:username}", separator and wildcard.
+// Concatenating the token values gives back s. Invalid input is tokenized as
+// well as possible, e.g. an unterminated "${" is literal, and an "arn:"
+// string with less than five ':' is a single prefix token.
+func TokenizeResource(s string) []Token {
+	tokens := []Token{}
+
+	offset := 0
+	if strings.HasPrefix(s, "arn:") {
+		// Prefix ends after the account, the fifth ':'.
+		offset = len(s)
+		colons := 0
+		for i := 0; i < len(s); i++ {
+			if s[i] == ':' {
+				colons++
+				if colons == 5 {
+					offset = i + 1
+					break
+				}
+			}
+		}
+		tokens = append(tokens, Token{Kind: PrefixTokenKind, Value: s[:offset], Offset: 0})
+	}
+
+	literalKind := BucketTokenKind
+	start := offset
+	flush := func(end int) {
+		if end > start {
+			tokens = append(tokens, Token{Kind: literalKind, Value: s[start:end], Offset: start})
+		}
+	}
+
+	for i := offset; i < len(s); {
+		switch {
+		case s[i] == '*' || s[i] == '?':
+			flush(i)
+			tokens = append(tokens, Token{Kind: WildcardTokenKind, Value: s[i : i+1], Offset: i})
+			i++
+		case s[i] == '/':
+			flush(i)
+			tokens = append(tokens, Token{Kind: SeparatorTokenKind, Value: "/", Offset: i})
+			literalKind = LiteralTokenKind
+			i++
+		case strings.HasPrefix(s[i:], "${") && strings.IndexByte(s[i:], '}') >= 0:
+			flush(i)
+			j := i + strings.IndexByte(s[i:], '}') + 1
+			tokens = append(tokens, Token{Kind: VariableTokenKind, Value: s[i:j], Offset: i})
+			i = j
+		default:
+			i++
+			continue
+		}
+		start = i
+	}
+	flush(len(s))
+
+	return tokens
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeResource(t *testing.T) {
+	testCases := []struct {
+		s              string
+		expectedResult []Token
+	}{
+		{"arn:aws:s3:::my*bucket/home/${aws:username}/photos-?/*.jpg", []Token{
+			{PrefixTokenKind, "arn:aws:s3:::", 0},
+			{BucketTokenKind, "my", 13},
+			{WildcardTokenKind, "*", 15},
+			{BucketTokenKind, "bucket", 16},
+			{SeparatorTokenKind, "/", 22},
+			{LiteralTokenKind, "home", 23},
+			{SeparatorTokenKind, "/", 27},
+			{VariableTokenKind, "${aws:username}", 28},
+			{SeparatorTokenKind, "/", 43},
+			{LiteralTokenKind, "photos-", 44},
+			{WildcardTokenKind, "?", 51},
+			{SeparatorTokenKind, "/", 52},
+			{WildcardTokenKind, "*", 53},
+			{LiteralTokenKind, ".jpg", 54},
+		}},
+		{"arn:aws:s3:us-east-1:111122223333:job/*", []Token{
+			{PrefixTokenKind, "arn:aws:s3:us-east-1:111122223333:", 0},
+			{BucketTokenKind, "job", 34},
+			{SeparatorTokenKind, "/", 37},
+			{WildcardTokenKind, "*", 38},
+		}},
+		{"mybucket/${aws:username}-${jwt:sub}", []Token{
+			{BucketTokenKind, "mybucket", 0},
+			{SeparatorTokenKind, "/", 8},
+			{VariableTokenKind, "${aws:username}", 9},
+			{LiteralTokenKind, "-", 24},
+			{VariableTokenKind, "${jwt:sub}", 25},
+		}},
+		{"${aws:username}//a", []Token{
+			{VariableTokenKind, "${aws:username}", 0},
+			{SeparatorTokenKind, "/", 15},
+			{SeparatorTokenKind, "/", 16},
+			{LiteralTokenKind, "a", 17},
+		}},
+		// Unterminated variables are literal.
+		{"mybucket/${aws:username*", []Token{
+			{BucketTokenKind, "mybucket", 0},
+			{SeparatorTokenKind, "/", 8},
+			{LiteralTokenKind, "${aws:username", 9},
+			{WildcardTokenKind, "*", 23},
+		}},
+		{"arn:aws:s3", []Token{
+			{PrefixTokenKind, "arn:aws:s3", 0},
+		}},
+		{"*", []Token{
+			{WildcardTokenKind, "*", 0},
+		}},
+		{"", []Token{}},
+	}
+
+	for i, testCase := range testCases {
+		result := TokenizeResource(testCase.s)
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+
+		var values []string
+		for _, token := range result {
+			if testCase.s[token.Offset:token.Offset+len(token.Value)] != token.Value {
+				t.Fatalf("case %v: token %v does not start at offset %v", i+1, token.Value, token.Offset)
+			}
+			values = append(values, token.Value)
+		}
+		if s := strings.Join(values, ""); s != testCase.s {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.s, s)
+		}
+	}
+}
+
+func TestTokenKindString(t *testing.T) {
+	testCases := []struct {
+		kind           TokenKind
+		expectedResult string
+	}{
+		{PrefixTokenKind, "prefix"},
+		{BucketTokenKind, "bucket"},
+		{SeparatorTokenKind, "separator"},
+		{LiteralTokenKind, "literal-segment"},
+		{WildcardTokenKind, "wildcard"},
+		{VariableTokenKind, "variable"},
+		{TokenKind(-1), "unknown"},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.kind.String()
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
@@ -19,19 +19,153 @@ package policy
 
 import (
 	"encoding/json"
+	"fmt"
 	"path"
+	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/trinet2005/oss-pkg/policy/condition"
 	"github.com/trinet2005/oss-pkg/wildcard"
 )
 
-// ResourceARNPrefix - resource ARN prefix as per AWS S3 specification.
+// DefaultPartition - default partition of resource ARNs.
+const DefaultPartition = "aws"
+
+// ResourceARNPrefix - resource ARN prefix as per AWS S3 specification, of
+// DefaultPartition. See ARNPrefix for the prefix of the partition set by
+// SetPartition.
 const ResourceARNPrefix = "arn:aws:s3:::"
 
+// resourceARNServicePrefix - ARN prefix up to the region and account fields,
+// of the partition set by SetPartition.
+var resourceARNServicePrefix = "arn:" + DefaultPartition + ":s3:"
+
+// partitionRegexp - valid partition, e.g. "aws" or "aws-cn".
+var partitionRegexp = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// SetPartition - sets the partition of resource ARNs rendered by
+// Resource.String and ARNPrefix, and accepted by parsing, e.g.
+// "custom-partition" for "arn:custom-partition:s3:::mybucket". ARNs of
+// other partitions, including "aws", are invalid afterwards. It is not safe
+// to call concurrently with any other function of the package, call it once
+// at init before parsing or rendering resources.
+func SetPartition(partition string) error {
+	if !partitionRegexp.MatchString(partition) {
+		return Errorf("invalid partition '%v'", partition)
+	}
+
+	resourceARNServicePrefix = "arn:" + partition + ":s3:"
+	return nil
+}
+
+// ARNPrefix - returns the resource ARN prefix of the partition set by
+// SetPartition, ResourceARNPrefix unless set.
+func ARNPrefix() string {
+	return resourceARNServicePrefix + "::"
+}
+
+// Partition - returns the partition of resource ARNs, DefaultPartition
+// unless set by SetPartition.
+func Partition() string {
+	return strings.TrimSuffix(strings.TrimPrefix(resourceARNServicePrefix, "arn:"), ":s3:")
+}
+
 // Resource - resource in policy statement.
 type Resource struct {
 	Pattern string
+
+	// Account is the optional account id of the ARN, plain S3 bucket and
+	// object ARNs do not carry one. Account is never matched as requests
+	// do not carry an account.
+	Account string
+
+	// region is the optional region of the ARN, see Region.
+	region string
+}
+
+// ResourceKind - kind of resource an ARN refers to.
+type ResourceKind int
+
+const (
+	// UnknownResourceKind - resource of unknown kind.
+	UnknownResourceKind ResourceKind = iota
+	// BucketResourceKind - bucket resource, e.g. "arn:aws:s3:::mybucket".
+	BucketResourceKind
+	// ObjectResourceKind - object resource, e.g. "arn:aws:s3:::mybucket/*".
+	ObjectResourceKind
+	// JobResourceKind - batch operations job, e.g.
+	// "arn:aws:s3:us-east-1:111122223333:job/myjob".
+	JobResourceKind
+	// StorageLensResourceKind - storage lens configuration, e.g.
+	// "arn:aws:s3:us-east-1:111122223333:storage-lens/mylens".
+	StorageLensResourceKind
+	// AccessPointResourceKind - access point, e.g.
+	// "arn:aws:s3:us-east-1:111122223333:accesspoint/myap".
+	AccessPointResourceKind
+)
+
+// controlPlaneResourcePrefixes - pattern prefixes of control-plane ARNs.
+var controlPlaneResourcePrefixes = map[string]ResourceKind{
+	"job/":          JobResourceKind,
+	"storage-lens/": StorageLensResourceKind,
+	"accesspoint/":  AccessPointResourceKind,
+}
+
+// String - returns name of the resource kind.
+func (kind ResourceKind) String() string {
+	switch kind {
+	case BucketResourceKind:
+		return "Bucket"
+	case ObjectResourceKind:
+		return "Object"
+	case JobResourceKind:
+		return "Job"
+	case StorageLensResourceKind:
+		return "StorageLens"
+	case AccessPointResourceKind:
+		return "AccessPoint"
+	}
+	return "Unknown"
+}
+
+// Kind - returns the kind of resource. Control-plane ARNs carry a region and
+// an account, e.g. "arn:aws:s3:us-east-1:111122223333:job/myjob", other
+// ARNs are bucket or object ARNs depending on whether the pattern contains
+// '/'. Note that a wildcard pattern without '/', e.g. "mybucket*", is
+// reported as Bucket although it matches objects as well.
+func (r Resource) Kind() ResourceKind {
+	if r.Pattern == "" {
+		return UnknownResourceKind
+	}
+
+	if r.region != "" && r.Account != "" {
+		for prefix, kind := range controlPlaneResourcePrefixes {
+			if strings.HasPrefix(r.Pattern, prefix) {
+				return kind
+			}
+		}
+	}
+
+	if strings.Contains(r.Pattern, "/") {
+		return ObjectResourceKind
+	}
+	return BucketResourceKind
+}
+
+// isControlPlane - returns whether the resource is a control-plane ARN.
+func (r Resource) isControlPlane() bool {
+	switch r.Kind() {
+	case JobResourceKind, StorageLensResourceKind, AccessPointResourceKind:
+		return true
+	}
+	return false
+}
+
+// matchable - returns whether the resource can match bucket and object
+// names, which control-plane resources never do.
+func (r Resource) matchable() bool {
+	return !r.isControlPlane()
 }
 
 func (r Resource) isBucketPattern() bool {
@@ -48,6 +182,14 @@ func (r Resource) IsValid() bool {
 		return false
 	}
 
+	if r.Account != "" && !isValidAccountID(r.Account) {
+		return false
+	}
+
+	if r.region != "" && !isValidRegion(r.region) {
+		return false
+	}
+
 	return r.Pattern != ""
 }
 
@@ -58,19 +200,562 @@ func (r Resource) MatchResource(resource string) bool {
 
 // Match - matches object name with resource pattern, including specific conditionals.
 func (r Resource) Match(resource string, conditionValues map[string][]string) bool {
-	pattern := r.Pattern
+	return r.MatchWithOptions(resource, conditionValues, MatchOptions{})
+}
+
+// MatchARN - matches a full ARN with the resource. Control-plane resources
+// match the ARN as a wildcard pattern, e.g.
+// "arn:aws:s3:us-east-1:111122223333:job/*" matches any job of the account
+// in the region. Bucket and object resources match the bucket and object
+// portion of a bucket or object ARN like MatchResource.
+func (r Resource) MatchARN(arn string) bool {
+	if r.isControlPlane() {
+		return wildcard.Match(r.String(), arn)
+	}
+
+	resource, err := parseResource(arn)
+	if err != nil || resource.isControlPlane() {
+		return false
+	}
+
+	return r.MatchResource(resource.Pattern)
+}
+
+// MatchObject - matches the object of a bucket with resource pattern,
+// including specific conditionals. bucket and object are joined like
+// statements do while evaluating policy args, i.e. an empty object refers
+// to the bucket itself, e.g. "mybucket/", and a leading '/' of object is
+// not doubled, e.g. "mybucket" and "/myobject" join to "mybucket/myobject".
+func (r Resource) MatchObject(bucket, object string, conditionValues map[string][]string) bool {
+	return r.Match(argsResource(Args{BucketName: bucket, ObjectName: object}), conditionValues)
+}
+
+// AllowsDelimitedListing - checks whether listing the objects of a bucket the
+// resource matches, with given prefix and delimiter, is consistent with the
+// object portion of the resource pattern, i.e. either
+//   - every object the listing may return matches the pattern, e.g. prefix
+//     "home/alice/" for "mybucket/home/alice/*", with or without delimiter;
+//   - or delimiter is not empty and prefix is empty or ends with delimiter
+//     and is a prefix of the literal portion of the pattern, e.g. prefix ""
+//     or "home/" with delimiter "/" for "mybucket/home/alice/*". Such
+//     listings lead to the objects of the pattern, but reveal names of
+//     objects and common prefixes at that level, e.g. "home/bob/".
+//
+// Resources of ListBucket statements name buckets and do not restrict
+// listings, which is done by "s3:prefix" and "s3:delimiter" conditions,
+// e.g. StringLike "s3:prefix": "home/alice/*" and StringEquals
+// "s3:delimiter": "/". This checks that listings such conditions allow are
+// consistent with an object resource. The bucket portion of the pattern is
+// not considered and policy variables match literally. Bucket resources
+// allow no listing.
+func (r Resource) AllowsDelimitedListing(prefix, delimiter string) bool {
+	if r.Kind() != ObjectResourceKind {
+		return false
+	}
+	object := strings.SplitN(r.Pattern, "/", 2)[1]
+
+	if wildcard.MatchPattern(object, prefix+"*") {
+		return true
+	}
+
+	if delimiter == "" || (prefix != "" && !strings.HasSuffix(prefix, delimiter)) {
+		return false
+	}
+	literal := object
+	if i := strings.IndexAny(literal, "*?"); i >= 0 {
+		literal = literal[:i]
+	}
+	if i := strings.Index(literal, "${"); i >= 0 {
+		literal = literal[:i]
+	}
+	return strings.HasPrefix(literal, prefix)
+}
+
+// MatchOptions - options to alter resource matching, the zero value
+// matches the same as Match.
+type MatchOptions struct {
+	// CaseInsensitiveBucket folds the case of the bucket portion, i.e. up
+	// to the first '/', of both the pattern and the resource. The object
+	// portion is always matched case sensitively, e.g. "MyBucket/Key"
+	// matches pattern "mybucket/Key" but not "mybucket/key".
+	CaseInsensitiveBucket bool
+
+	// SingleWildcard and MultiWildcard replace '?' and '*' as the
+	// wildcards matching a single character and any characters, e.g. '_'
+	// and '%' for SQL LIKE style patterns, zero values keep the defaults.
+	// The wildcards must differ. As patterns have no escaping, '?' and '*'
+	// match literally once replaced, while the replacements can not be
+	// matched literally.
+	SingleWildcard rune
+	MultiWildcard  rune
+
+	// SingleWildcardNoSlash and MultiWildcardNoSlash stop the single and
+	// multi character wildcards from matching '/', like '?' and '*' of
+	// shell globs. They apply to the configured wildcards and are
+	// independent of each other, e.g. whether "mybucket/*" matches
+	// "mybucket/a/b" and "mybucket/a?c" matches "mybucket/a/c":
+	//
+	//	SingleWildcardNoSlash  MultiWildcardNoSlash  "mybucket/*"  "mybucket/a?c"
+	//	false                  false                 yes           yes
+	//	false                  true                  no            yes
+	//	true                   false                 yes           no
+	//	true                   true                  no            no
+	//
+	// Either wildcard still matches any other character, and a pattern
+	// without wildcards matches as usual.
+	SingleWildcardNoSlash bool
+	MultiWildcardNoSlash  bool
+
+	// CollapseSlashes path cleans the resource before comparing it with a
+	// pattern without wildcards, i.e. consecutive '/' are collapsed and '.'
+	// and '..' elements resolved, e.g. "mybucket//a" matches pattern
+	// "mybucket/a". S3 keys may contain consecutive '/' and "a//b" is a
+	// different key than "a/b", so cleaning can match keys the pattern does
+	// not name. When false, resources are compared as is except that a
+	// trailing '/' is ignored, e.g. "mybucket/" still matches "mybucket".
+	// Wildcards match '/' in both cases. Defaults to true when nil.
+	CollapseSlashes *bool
+
+	// RestrictVariableSlashes fails the match when a policy variable of the
+	// pattern is substituted by a value containing '/'. Variables like
+	// "${aws:username}" usually stand for a single path segment, e.g. in
+	// "mybucket/home/${aws:username}/*", and a crafted username such as
+	// "alice/private" would otherwise grant access to objects of user
+	// "alice". Note that '*' and '?' of substituted values still act as
+	// wildcards.
+	RestrictVariableSlashes bool
+
+	// StripLeadingSegments removes that many '/' separated segments of the
+	// object portion, i.e. after the bucket, of the resource before
+	// matching, e.g. with 1 "mybucket/tenant1/a/b" is matched as
+	// "mybucket/a/b". Bucket resources are matched as is, while objects
+	// having no more than the stripped segments, e.g. "mybucket/tenant1/",
+	// never match. Stripped segments are not authorized by the policy at
+	// all, whoever injects them, e.g. a gateway prefixing keys by tenant,
+	// must ensure callers can not choose them, otherwise access to an
+	// object grants access to the object of every tenant.
+	StripLeadingSegments int
+}
+
+// collapseSlashes - returns whether resources are path cleaned.
+func (opts MatchOptions) collapseSlashes() bool {
+	return opts.CollapseSlashes == nil || *opts.CollapseSlashes
+}
+
+// metachars - returns the single and multi character wildcards.
+func (opts MatchOptions) metachars() (single, multi rune) {
+	single, multi = '?', '*'
+	if opts.SingleWildcard != 0 {
+		single = opts.SingleWildcard
+	}
+	if opts.MultiWildcard != 0 {
+		multi = opts.MultiWildcard
+	}
+	return single, multi
+}
+
+// MatchWithOptions - matches object name with resource pattern, including
+// specific conditionals, as per given options.
+func (r Resource) MatchWithOptions(resource string, conditionValues map[string][]string, opts MatchOptions) bool {
+	pattern, resource, matched, decided := r.prepareMatch(resource, conditionValues, opts)
+	if decided {
+		return matched
+	}
+	if opts.SingleWildcardNoSlash || opts.MultiWildcardNoSlash {
+		single, multi := opts.metachars()
+		return wildcard.MatchWithSlashes(pattern, resource, single, multi, !opts.SingleWildcardNoSlash, !opts.MultiWildcardNoSlash)
+	}
+	if single, multi := opts.metachars(); single != '?' || multi != '*' {
+		return wildcard.MatchWithMetachars(pattern, resource, single, multi)
+	}
+	return wildcard.Match(pattern, resource)
+}
+
+// prepareMatch - returns the substituted pattern and resource to wildcard
+// match as per opts, unless matching is decided without wildcard matching,
+// e.g. for control-plane resources or exact matches.
+func (r Resource) prepareMatch(resource string, conditionValues map[string][]string, opts MatchOptions) (pattern, name string, matched, decided bool) {
+	if !r.matchable() {
+		return "", "", false, true
+	}
+
+	if opts.StripLeadingSegments > 0 {
+		var ok bool
+		if resource, ok = stripLeadingSegments(resource, opts.StripLeadingSegments); !ok {
+			return "", "", false, true
+		}
+	}
+
+	if opts.RestrictVariableSlashes && r.substitutesSlash(conditionValues) {
+		return "", "", false, true
+	}
+
+	pattern = r.substituteVariables(conditionValues).Pattern
+	if opts.CaseInsensitiveBucket {
+		pattern = lowerBucket(pattern)
+		resource = lowerBucket(resource)
+	}
+	if opts.collapseSlashes() {
+		if cp := path.Clean(resource); cp != "." && cp == pattern {
+			return "", "", true, true
+		}
+	} else if strings.TrimSuffix(resource, "/") == pattern {
+		return "", "", true, true
+	}
+	return pattern, resource, false, false
+}
+
+// DefaultMatchRecursionLimit - limit of matching steps used by
+// MatchWithRecursionGuard when a non-positive limit is given. Realistic
+// patterns take at most a few thousand steps.
+const DefaultMatchRecursionLimit = 100000
+
+// MatchWithRecursionGuard - matches like Match, but returns an error instead
+// of matching further once more than limit wildcard matching steps are
+// taken, DefaultMatchRecursionLimit if limit is not positive. Patterns with
+// many '*' like "mybucket/*a*a*a*a*b" backtrack exponentially against long
+// resources, guarding bounds the time and stack spent matching untrusted
+// patterns.
+func (r Resource) MatchWithRecursionGuard(resource string, conditionValues map[string][]string, limit int) (bool, error) {
+	if limit <= 0 {
+		limit = DefaultMatchRecursionLimit
+	}
+
+	pattern, name, matched, decided := r.prepareMatch(resource, conditionValues, MatchOptions{})
+	if decided {
+		return matched, nil
+	}
+
+	matched, err := wildcard.MatchWithLimit(pattern, name, limit)
+	if err != nil {
+		return false, Errorf("matching resource '%v' with %v: %w", resource, r, err)
+	}
+	return matched, nil
+}
+
+// substituteVariables - returns the resource with policy variables of the
+// pattern replaced by given condition values.
+func (r Resource) substituteVariables(conditionValues map[string][]string) Resource {
 	if len(conditionValues) != 0 {
-		for _, key := range condition.CommonKeys {
-			// Empty values are not supported for policy variables.
-			if rvalues, ok := conditionValues[key.Name()]; ok && rvalues[0] != "" {
-				pattern = strings.Replace(pattern, key.VarName(), rvalues[0], -1)
+		r.Pattern = condition.SubstituteVariables(r.Pattern, conditionValues)
+	}
+	return r
+}
+
+// stripLeadingSegments - returns resource without n leading segments of its
+// object portion, false when no object key remains.
+func stripLeadingSegments(resource string, n int) (string, bool) {
+	tokens := strings.SplitN(resource, "/", 2)
+	if len(tokens) < 2 || tokens[1] == "" {
+		return resource, true
+	}
+
+	segments := strings.SplitN(tokens[1], "/", n+1)
+	if len(segments) <= n || segments[n] == "" {
+		return "", false
+	}
+	return tokens[0] + "/" + segments[n], true
+}
+
+// substitutesSlash - checks whether a policy variable of the pattern is
+// substituted by a value containing '/'.
+func (r Resource) substitutesSlash(conditionValues map[string][]string) bool {
+	for _, key := range condition.CommonKeys {
+		if rvalues := conditionValues[key.Name()]; len(rvalues) > 0 && strings.Contains(rvalues[0], "/") &&
+			strings.Contains(r.Pattern, key.VarName()) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchSegment - portion of a resource consumed by one token of a pattern.
+type MatchSegment struct {
+	// Token is the pattern token, either a literal run, "*" or "?".
+	Token string
+	// Literal is true when Token is a literal run of the pattern.
+	Literal bool
+	// Offset is the rune offset in the resource where Consumed starts.
+	Offset int
+	// Consumed is the portion of the resource matched by Token.
+	Consumed string
+}
+
+// MatchDebugInfo - describes how a resource pattern matched a resource.
+type MatchDebugInfo struct {
+	Pattern  string
+	Resource string
+	// Exact is true when the cleaned resource equals the pattern.
+	Exact bool
+	// Wildcard is true when the resource matches the pattern as a wildcard.
+	Wildcard bool
+	// Segments lists what each pattern token consumed, only set when
+	// Wildcard is true.
+	Segments []MatchSegment
+}
+
+// Matched - returns whether the resource was matched by the pattern.
+func (info MatchDebugInfo) Matched() bool {
+	return info.Exact || info.Wildcard
+}
+
+// MatchDebug - matches resource like MatchResource and reports how the
+// pattern was matched. Wildcards are expanded the same way as in
+// wildcard.Match, i.e. each '*' consumes as little as possible.
+func (r Resource) MatchDebug(resource string) MatchDebugInfo {
+	info := MatchDebugInfo{
+		Pattern:  r.Pattern,
+		Resource: resource,
+	}
+	if cp := path.Clean(resource); cp != "." && cp == r.Pattern {
+		info.Exact = true
+	}
+	if r.Pattern == "" {
+		info.Wildcard = resource == ""
+		return info
+	}
+	segments, ok := matchSegments([]rune(resource), tokenizePattern(r.Pattern), 0)
+	if ok {
+		info.Wildcard = true
+		info.Segments = segments
+	}
+	return info
+}
+
+// tokenizePattern - splits pattern into literal runs and single '*' or '?'.
+func tokenizePattern(pattern string) []string {
+	var tokens []string
+	literal := []rune{}
+	for _, c := range pattern {
+		if c != '*' && c != '?' {
+			literal = append(literal, c)
+			continue
+		}
+		if len(literal) > 0 {
+			tokens = append(tokens, string(literal))
+			literal = literal[:0]
+		}
+		tokens = append(tokens, string(c))
+	}
+	if len(literal) > 0 {
+		tokens = append(tokens, string(literal))
+	}
+	return tokens
+}
+
+func matchSegments(str []rune, tokens []string, offset int) ([]MatchSegment, bool) {
+	if len(tokens) == 0 {
+		return nil, len(str) == 0
+	}
+
+	token := tokens[0]
+	switch token {
+	case "*":
+		for i := 0; i <= len(str); i++ {
+			if rest, ok := matchSegments(str[i:], tokens[1:], offset+i); ok {
+				segment := MatchSegment{Token: token, Offset: offset, Consumed: string(str[:i])}
+				return append([]MatchSegment{segment}, rest...), true
 			}
 		}
+		return nil, false
+	case "?":
+		if len(str) == 0 {
+			return nil, false
+		}
+		rest, ok := matchSegments(str[1:], tokens[1:], offset+1)
+		if !ok {
+			return nil, false
+		}
+		segment := MatchSegment{Token: token, Offset: offset, Consumed: string(str[:1])}
+		return append([]MatchSegment{segment}, rest...), true
+	default:
+		literal := []rune(token)
+		if len(str) < len(literal) || string(str[:len(literal)]) != token {
+			return nil, false
+		}
+		rest, ok := matchSegments(str[len(literal):], tokens[1:], offset+len(literal))
+		if !ok {
+			return nil, false
+		}
+		segment := MatchSegment{Token: token, Literal: true, Offset: offset, Consumed: token}
+		return append([]MatchSegment{segment}, rest...), true
 	}
-	if cp := path.Clean(resource); cp != "." && cp == pattern {
-		return true
+}
+
+// Canonical - returns a copy of the resource with redundant wildcards of the
+// pattern removed by wildcard.Simplify, e.g. "mybucket/**" becomes
+// "mybucket/*". The copy matches the same resources, except those which only
+// match the literal pattern after path cleaning, e.g. "mybucket/a**b/".
+func (r Resource) Canonical() Resource {
+	r.Pattern = wildcard.Simplify(r.Pattern)
+	return r
+}
+
+// CanonicalARN - returns the ARN of the resource in a normalized form for
+// comparing and signing resources, e.g. "arn:aws:s3:::mybucket/a/*" for
+// "arn:aws:s3:::mybucket//a/**". The pattern is normalized by Canonical and
+// by collapsing consecutive '/' into one, like requested resources are path
+// cleaned. Unlike Canonical, the result may match more resources, as
+// "mybucket/a/*" matches "mybucket/a/b" but "mybucket//a/*" does not. These
+// rules are part of the ARN format and must not change.
+func (r Resource) CanonicalARN() string {
+	r = r.Canonical()
+	for strings.Contains(r.Pattern, "//") {
+		r.Pattern = strings.ReplaceAll(r.Pattern, "//", "/")
 	}
-	return wildcard.Match(pattern, resource)
+	return r.String()
+}
+
+// DiffMatchExamples - maximum number of examples DiffMatch returns for each
+// resource.
+const DiffMatchExamples = 10
+
+// diffMatchCandidates - maximum number of candidates generated per pattern.
+const diffMatchCandidates = 4096
+
+// DiffMatch - returns sorted example resources matched by r but not by
+// other, and by other but not by r, at most DiffMatchExamples each, e.g.
+// "mybucket/a" only for "mybucket/*" compared to "mybucket/logs/*".
+// Examples are built from both patterns by replacing each '*' with one of
+// "", "a", "/" and "a/a", and each '?' with "a" or "/", hence empty results
+// suggest, but do not prove, that both match the same resources. Policy
+// variables are kept literally, and control-plane resources match no
+// examples.
+func (r Resource) DiffMatch(other Resource) (onlyR, onlyOther []string) {
+	candidates := map[string]struct{}{}
+	for _, pattern := range []string{r.Pattern, other.Pattern} {
+		for _, candidate := range instantiatePattern(pattern, diffMatchCandidates) {
+			candidates[candidate] = struct{}{}
+		}
+	}
+
+	onlyR, onlyOther = []string{}, []string{}
+	for candidate := range candidates {
+		matchR, matchOther := r.MatchResource(candidate), other.MatchResource(candidate)
+		switch {
+		case matchR && !matchOther:
+			onlyR = append(onlyR, candidate)
+		case matchOther && !matchR:
+			onlyOther = append(onlyOther, candidate)
+		}
+	}
+
+	for _, examples := range []*[]string{&onlyR, &onlyOther} {
+		sort.Strings(*examples)
+		if len(*examples) > DiffMatchExamples {
+			*examples = (*examples)[:DiffMatchExamples]
+		}
+	}
+	return onlyR, onlyOther
+}
+
+// instantiatePattern - returns up to limit strings matched by pattern, see
+// DiffMatch for how wildcards are replaced.
+func instantiatePattern(pattern string, limit int) []string {
+	results := []string{""}
+	for _, c := range pattern {
+		var fillers []string
+		switch c {
+		case '*':
+			fillers = []string{"", "a", "/", "a/a"}
+		case '?':
+			fillers = []string{"a", "/"}
+		default:
+			fillers = []string{string(c)}
+		}
+
+		var next []string
+		for _, prefix := range results {
+			for _, filler := range fillers {
+				if len(next) < limit {
+					next = append(next, prefix+filler)
+				}
+			}
+		}
+		results = next
+	}
+	return results
+}
+
+// EnclosingBucketResource - returns the tightest bucket resource enclosing
+// all resources matched by r, e.g. "mybucket" for "mybucket/logs/*".
+//
+// As '*' and '?' may match '/', a bucket portion containing a wildcard
+// is reduced to its literal prefix followed by '*', e.g. "my*bucket/logs"
+// gives "my*" and "*" or "*/*" gives "*", i.e. all buckets.
+func (r Resource) EnclosingBucketResource() Resource {
+	bucket := r.Pattern
+	if i := strings.Index(bucket, "/"); i >= 0 {
+		bucket = bucket[:i]
+	}
+	if i := strings.IndexAny(bucket, "*?"); i >= 0 {
+		bucket = bucket[:i] + "*"
+	}
+	return Resource{Pattern: bucket, Account: r.Account, region: r.region}
+}
+
+// Describe - returns a plain English description of the resource, e.g.
+// "all objects under prefix 'logs/' in bucket 'mybucket'".
+func (r Resource) Describe() string {
+	var desc string
+
+	bucket, object, hasObject := strings.Cut(r.Pattern, "/")
+	bucketDesc := fmt.Sprintf("bucket '%s'", bucket)
+	if strings.ContainsAny(bucket, "*?") {
+		bucketDesc = fmt.Sprintf("buckets matching '%s'", bucket)
+	}
+
+	switch {
+	case r.Pattern == "*" || r.Pattern == "*/*":
+		desc = "all buckets and objects"
+	case !hasObject && strings.ContainsAny(bucket, "*?"):
+		desc = fmt.Sprintf("all buckets and objects matching '%s'", bucket)
+	case !hasObject || object == "":
+		desc = "the entire " + bucketDesc
+	case object == "*":
+		desc = "all objects in " + bucketDesc
+	case strings.IndexAny(object, "*?") == len(object)-1 && strings.HasSuffix(object, "*"):
+		desc = fmt.Sprintf("all objects under prefix '%s' in %s", strings.TrimSuffix(object, "*"), bucketDesc)
+	case strings.ContainsAny(object, "*?"):
+		desc = fmt.Sprintf("objects matching '%s' in %s", object, bucketDesc)
+	default:
+		desc = fmt.Sprintf("the object '%s' in %s", object, bucketDesc)
+	}
+
+	switch vars := policyVariables(r.Pattern); len(vars) {
+	case 0:
+	case 1:
+		desc += fmt.Sprintf(", where %s is substituted per request", vars[0])
+	default:
+		desc += fmt.Sprintf(", where %s are substituted per request", strings.Join(vars, ", "))
+	}
+
+	return desc
+}
+
+// regexpString - returns the unanchored regular expression equivalent of
+// the wildcard pattern.
+func (r Resource) regexpString() string {
+	var sb strings.Builder
+	for _, token := range tokenizePattern(r.Pattern) {
+		switch token {
+		case "*":
+			sb.WriteString(".*")
+		case "?":
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(token))
+		}
+	}
+	return sb.String()
+}
+
+// lowerBucket - returns s with the portion up to the first '/' in lower case.
+func lowerBucket(s string) string {
+	if i := strings.Index(s, "/"); i >= 0 {
+		return strings.ToLower(s[:i]) + s[i:]
+	}
+	return strings.ToLower(s)
 }
 
 // MarshalJSON - encodes Resource to JSON data.
@@ -83,7 +768,49 @@ func (r Resource) MarshalJSON() ([]byte, error) {
 }
 
 func (r Resource) String() string {
-	return ResourceARNPrefix + r.Pattern
+	if r.region == "" && r.Account == "" {
+		return ARNPrefix() + r.Pattern
+	}
+	return resourceARNServicePrefix + r.region + ":" + r.Account + ":" + r.Pattern
+}
+
+// WithAccount - returns a copy of the resource with the account id set to
+// accountID, an empty accountID clears it. As S3 bucket and object ARNs
+// omit the account, this turns e.g. "arn:aws:s3:::mybucket/*" into
+// "arn:aws:s3::111122223333:mybucket/*", which matches the same resources.
+func (r Resource) WithAccount(accountID string) Resource {
+	r.Account = accountID
+	return r
+}
+
+// Region - returns the region of the ARN, e.g. "us-east-1" for
+// "arn:aws:s3:us-east-1::mybucket/*", which is empty for plain S3 bucket
+// and object ARNs. Like Account, the region is never matched as requests do
+// not carry it.
+func (r Resource) Region() string {
+	return r.region
+}
+
+// regionRegexp - regular expression of region names like "us-east-1" and
+// "us-gov-west-1".
+var regionRegexp = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// isValidRegion - checks whether s is a valid region name.
+func isValidRegion(s string) bool {
+	return regionRegexp.MatchString(s)
+}
+
+// isValidAccountID - checks whether s is a 12 digit account id.
+func isValidAccountID(s string) bool {
+	if len(s) != 12 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
 }
 
 // UnmarshalJSON - decodes JSON data to Resource.
@@ -111,6 +838,28 @@ func (r Resource) Validate() error {
 	return nil
 }
 
+// ValidateMaxWildcards - validates that the pattern has at most n wildcard
+// segments. A wildcard segment is a maximal run of consecutive '*', so
+// "mybucket/**" has one and "*/photos/*.jpg" has two. '?' matches a single
+// character and is not counted, neither are policy variables.
+func (r Resource) ValidateMaxWildcards(n int) error {
+	if count := wildcardSegments(r.Pattern); count > n {
+		return Errorf("resource '%v' has %v wildcard segments, at most %v allowed", r, count, n)
+	}
+	return nil
+}
+
+// wildcardSegments - returns the number of runs of consecutive '*' in pattern.
+func wildcardSegments(pattern string) int {
+	count := 0
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] == '*' && (i == 0 || pattern[i-1] != '*') {
+			count++
+		}
+	}
+	return count
+}
+
 // ValidateBucket - validates that given bucketName is matched by Resource.
 func (r Resource) ValidateBucket(bucketName string) error {
 	if !r.IsValid() {
@@ -125,6 +874,10 @@ func (r Resource) ValidateBucket(bucketName string) error {
 	//   2. bucket name followed by '/' must match as a prefix of the resource
 	//   pattern (e.g. `example*a` includes resources in a bucket 'example22'
 	//   for example the object `example22/2023/a` is matched by this resource).
+	//
+	// Only the leading path segment names the bucket, e.g. `bucket/bucket/x`
+	// validates for bucket 'bucket' but neither for 'bucketbucket' nor does
+	// `other/bucket/x` validate for 'bucket'.
 	if !wildcard.Match(r.Pattern, bucketName) &&
 		!wildcard.MatchAsPatternPrefix(r.Pattern, bucketName+"/") {
 
@@ -134,22 +887,50 @@ func (r Resource) ValidateBucket(bucketName string) error {
 	return nil
 }
 
-// parseResource - parses string to Resource.
+// parseResource - parses string to Resource. Only the region and account
+// fields following "arn:aws:s3:" are split on ':', the remainder is the
+// pattern as is, hence object keys may contain ':' (e.g.
+// "arn:aws:s3:::mybucket/a:b/c"). The pattern is interned if enabled, see
+// EnableInterning.
 func parseResource(s string) (Resource, error) {
-	if !strings.HasPrefix(s, ResourceARNPrefix) {
+	if !strings.HasPrefix(s, resourceARNServicePrefix) {
 		return Resource{}, Errorf("invalid resource '%v'", s)
 	}
 
-	pattern := strings.TrimPrefix(s, ResourceARNPrefix)
+	fields := strings.SplitN(strings.TrimPrefix(s, resourceARNServicePrefix), ":", 3)
+	if len(fields) != 3 {
+		return Resource{}, Errorf("invalid resource '%v'", s)
+	}
+
+	region, account, pattern := fields[0], fields[1], fields[2]
+	if region != "" && !isValidRegion(region) {
+		return Resource{}, Errorf("invalid resource '%v' - invalid region '%v'", s, region)
+	}
+
+	if account != "" && !isValidAccountID(account) {
+		return Resource{}, Errorf("invalid resource '%v' - invalid account id '%v'", s, account)
+	}
+
 	if strings.HasPrefix(pattern, "/") {
 		return Resource{}, Errorf("invalid resource '%v' - starts with '/' will not match a bucket", s)
 	}
 
 	return Resource{
-		Pattern: pattern,
+		Pattern: intern(pattern),
+		Account: account,
+		region:  region,
 	}, nil
 }
 
+// ParseResource - parses a resource ARN, e.g. "arn:aws:s3:::mybucket/*", as
+// per given options.
+func ParseResource(s string, opts ParseOptions) (Resource, error) {
+	if opts.LenientARNPrefix {
+		s = normalizeARNPrefix(s)
+	}
+	return parseResource(s)
+}
+
 // NewResource - creates new resource.
 func NewResource(pattern string) Resource {
 	return Resource{
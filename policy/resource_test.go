@@ -18,9 +18,15 @@
 package policy
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/trinet2005/oss-pkg/policy/condition"
+	"github.com/trinet2005/oss-pkg/wildcard"
 )
 
 func TestResourceIsBucketPattern(t *testing.T) {
@@ -119,6 +125,10 @@ func TestResourceMatch(t *testing.T) {
 		{NewResource("*/*"), "mybucket", false},
 		{NewResource("mybucket/*"), "mybucket10/myobject", false},
 		{NewResource("mybucket?0/2010/photos/*"), "mybucket0/2010/photos/1.jpg", false},
+		{NewResource("mybucket/logs/*.log"), "mybucket/logs/a.log", true},
+		{NewResource("mybucket/logs/*.log"), "mybucket/logs/.log", true},
+		{NewResource("mybucket/logs/*.log"), "mybucket/logs/a.txt", false},
+		{NewResource("mybucket/logs/*.log"), "mybucket/logs/a.log.txt", false},
 		{NewResource("mybucket"), "mybucket/myobject", false},
 	}
 
@@ -178,8 +188,18 @@ func TestResourceUnmarshalJSON(t *testing.T) {
 		{[]byte(`"arn:aws:s3:::mybucket/*"`), NewResource("mybucket/*"), false},
 		{[]byte(`"arn:aws:s3:::mybucket*/myobject"`), NewResource("mybucket*/myobject"), false},
 		{[]byte(`"arn:aws:s3:::mybucket?0/2010/photos/*"`), NewResource("mybucket?0/2010/photos/*"), false},
+		{[]byte(`"arn:aws:s3:::mybucket/a:b/c"`), NewResource("mybucket/a:b/c"), false},
+		{[]byte(`"arn:aws:s3:::mybucket/arn:aws:s3:::c"`), NewResource("mybucket/arn:aws:s3:::c"), false},
 		{[]byte(`"mybucket/myobject*"`), Resource{}, true},
 		{[]byte(`"arn:aws:s3:::/*"`), Resource{}, true},
+		{[]byte(`"arn:aws:s3:us-east-1:mybucket/a:b"`), Resource{}, true},
+		{[]byte(`"arn:aws:s3::111122223333:mybucket/*"`), NewResource("mybucket/*").WithAccount("111122223333"), false},
+		{[]byte(`"arn:aws:s3::1234:mybucket/*"`), Resource{}, true},
+		{[]byte(`"arn:aws:s3:"`), Resource{}, true},
+		{[]byte(`"arn:aws:s3:us-east-1:111122223333:job/myjob"`), Resource{Pattern: "job/myjob", Account: "111122223333", region: "us-east-1"}, false},
+		{[]byte(`"arn:aws:s3:us-east-1::job/myjob"`), Resource{Pattern: "job/myjob", region: "us-east-1"}, false},
+		{[]byte(`"arn:aws:s3:us-east-1:111122223333:mybucket/myjob"`), Resource{Pattern: "mybucket/myjob", Account: "111122223333", region: "us-east-1"}, false},
+		{[]byte(`"arn:aws:s3:US-EAST-1::mybucket/*"`), Resource{}, true},
 	}
 
 	for i, testCase := range testCases {
@@ -234,6 +254,16 @@ func TestResourceValidateBucket(t *testing.T) {
 		// corner cases for the given patterns and buckets.
 		{NewResource("mybucket*a/myobject*"), "mybucket", false},
 		{NewResource("mybucket*a/myobject*"), "mybucket22", false},
+
+		// Object paths repeating the bucket name only belong to the bucket
+		// of the leading path segment.
+		{NewResource("bucket/bucket/x"), "bucket", false},
+		{NewResource("bucket/bucket/*"), "bucket", false},
+		{NewResource("bucket/bucket"), "bucket", false},
+		{NewResource("bucketbucket/x"), "bucket", true},
+		{NewResource("bucket/bucket/x"), "bucketbucket", true},
+		{NewResource("other/bucket/x"), "bucket", true},
+		{NewResource("bucket*/bucket/x"), "bucketbucket", false},
 	}
 
 	for i, testCase := range testCases {
@@ -245,3 +275,1002 @@ func TestResourceValidateBucket(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceMatchDebug(t *testing.T) {
+	testCases := []struct {
+		resource         Resource
+		objectName       string
+		expectedExact    bool
+		expectedWildcard bool
+		expectedSegments []MatchSegment
+	}{
+		{NewResource("mybucket/myobject"), "mybucket/myobject", true, true, []MatchSegment{
+			{Token: "mybucket/myobject", Literal: true, Offset: 0, Consumed: "mybucket/myobject"},
+		}},
+		{NewResource("mybucket/*"), "mybucket/2010/photo.jpg", false, true, []MatchSegment{
+			{Token: "mybucket/", Literal: true, Offset: 0, Consumed: "mybucket/"},
+			{Token: "*", Offset: 9, Consumed: "2010/photo.jpg"},
+		}},
+		{NewResource("mybucket?0/*/*.jpg"), "mybucket20/2010/photo.jpg", false, true, []MatchSegment{
+			{Token: "mybucket", Literal: true, Offset: 0, Consumed: "mybucket"},
+			{Token: "?", Offset: 8, Consumed: "2"},
+			{Token: "0/", Literal: true, Offset: 9, Consumed: "0/"},
+			{Token: "*", Offset: 11, Consumed: "2010"},
+			{Token: "/", Literal: true, Offset: 15, Consumed: "/"},
+			{Token: "*", Offset: 16, Consumed: "photo"},
+			{Token: ".jpg", Literal: true, Offset: 21, Consumed: ".jpg"},
+		}},
+		{NewResource("mybucket"), "mybucket/", true, false, nil},
+		{NewResource("mybucket/*"), "mybucket10/myobject", false, false, nil},
+	}
+
+	for i, testCase := range testCases {
+		info := testCase.resource.MatchDebug(testCase.objectName)
+
+		if info.Pattern != testCase.resource.Pattern {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.resource.Pattern, info.Pattern)
+		}
+		if info.Exact != testCase.expectedExact {
+			t.Fatalf("case %v: exact: expected: %v, got: %v", i+1, testCase.expectedExact, info.Exact)
+		}
+		if info.Wildcard != testCase.expectedWildcard {
+			t.Fatalf("case %v: wildcard: expected: %v, got: %v", i+1, testCase.expectedWildcard, info.Wildcard)
+		}
+		if info.Matched() != testCase.resource.MatchResource(testCase.objectName) {
+			t.Fatalf("case %v: matched: expected: %v, got: %v", i+1, testCase.resource.MatchResource(testCase.objectName), info.Matched())
+		}
+		if !reflect.DeepEqual(info.Segments, testCase.expectedSegments) {
+			t.Fatalf("case %v: segments: expected: %v, got: %v", i+1, testCase.expectedSegments, info.Segments)
+		}
+	}
+}
+
+func TestResourceColonInObjectKey(t *testing.T) {
+	testCases := []struct {
+		data           string
+		objectName     string
+		expectedResult bool
+	}{
+		{`"arn:aws:s3:::mybucket/a:b/c"`, "mybucket/a:b/c", true},
+		{`"arn:aws:s3:::mybucket/a:b/*"`, "mybucket/a:b/c:d", true},
+		{`"arn:aws:s3:::mybucket/*:*"`, "mybucket/2023-01-01T00:00:00Z", true},
+		{`"arn:aws:s3:::mybucket/a:b/*"`, "mybucket/a/b/c", false},
+		{`"arn:aws:s3:::mybucket/a:b/c"`, "mybucket/a:b", false},
+	}
+
+	for i, testCase := range testCases {
+		var resource Resource
+		if err := json.Unmarshal([]byte(testCase.data), &resource); err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		result := resource.Match(testCase.objectName, nil)
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+
+		data, err := json.Marshal(resource)
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+		if string(data) != testCase.data {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.data, string(data))
+		}
+	}
+}
+
+func TestResourceEnclosingBucketResource(t *testing.T) {
+	testCases := []struct {
+		resource       Resource
+		expectedResult Resource
+	}{
+		{NewResource("mybucket/logs/*"), NewResource("mybucket")},
+		{NewResource("mybucket/myobject"), NewResource("mybucket")},
+		{NewResource("mybucket/"), NewResource("mybucket")},
+		{NewResource("mybucket"), NewResource("mybucket")},
+		{NewResource("mybucket*/myobject"), NewResource("mybucket*")},
+		{NewResource("my*bucket/logs/*"), NewResource("my*")},
+		{NewResource("mybucket?0/2010/photos/*"), NewResource("mybucket*")},
+		{NewResource("*/*"), NewResource("*")},
+		{NewResource("*"), NewResource("*")},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.EnclosingBucketResource()
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceDescribe(t *testing.T) {
+	testCases := []struct {
+		resource       Resource
+		expectedResult string
+	}{
+		{NewResource("*"), "all buckets and objects"},
+		{NewResource("*/*"), "all buckets and objects"},
+		{NewResource("mybucket"), "the entire bucket 'mybucket'"},
+		{NewResource("mybucket*"), "all buckets and objects matching 'mybucket*'"},
+		{NewResource("mybucket/*"), "all objects in bucket 'mybucket'"},
+		{NewResource("mybucket/logs/*"), "all objects under prefix 'logs/' in bucket 'mybucket'"},
+		{NewResource("mybucket/logs/*.log"), "objects matching 'logs/*.log' in bucket 'mybucket'"},
+		{NewResource("mybucket/logs/a.log"), "the object 'logs/a.log' in bucket 'mybucket'"},
+		{NewResource("mybucket?0/*"), "all objects in buckets matching 'mybucket?0'"},
+		{NewResource("mybucket/${aws:username}/*"), "all objects under prefix '${aws:username}/' in bucket 'mybucket', where ${aws:username} is substituted per request"},
+		{NewResource("${jwt:sub}/${aws:username}/${jwt:sub}"), "the object '${aws:username}/${jwt:sub}' in bucket '${jwt:sub}', where ${jwt:sub}, ${aws:username} are substituted per request"},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.Describe()
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceMatchCaseInsensitiveBucket(t *testing.T) {
+	testCases := []struct {
+		resource       Resource
+		objectName     string
+		opts           MatchOptions
+		expectedResult bool
+	}{
+		{NewResource("mybucket/MyObject"), "MyBucket/MyObject", MatchOptions{}, false},
+		{NewResource("mybucket/MyObject"), "MyBucket/MyObject", MatchOptions{CaseInsensitiveBucket: true}, true},
+		{NewResource("MyBucket/MyObject"), "mybucket/MyObject", MatchOptions{CaseInsensitiveBucket: true}, true},
+		{NewResource("mybucket/MyObject"), "MyBucket/myobject", MatchOptions{CaseInsensitiveBucket: true}, false},
+		{NewResource("mybucket"), "MYBUCKET", MatchOptions{CaseInsensitiveBucket: true}, true},
+		{NewResource("mybucket"), "MYBUCKET/", MatchOptions{CaseInsensitiveBucket: true}, true},
+		{NewResource("my*/Photos/*"), "MyBucket/Photos/1.JPG", MatchOptions{CaseInsensitiveBucket: true}, true},
+		{NewResource("my*/Photos/*"), "MyBucket/photos/1.JPG", MatchOptions{CaseInsensitiveBucket: true}, false},
+		{NewResource("mybucket?0/*"), "MYBUCKET20/Key", MatchOptions{CaseInsensitiveBucket: true}, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.MatchWithOptions(testCase.objectName, nil, testCase.opts)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceMatchMetachars(t *testing.T) {
+	sql := MatchOptions{SingleWildcard: '_', MultiWildcard: '%'}
+
+	testCases := []struct {
+		resource       Resource
+		objectName     string
+		opts           MatchOptions
+		expectedResult bool
+	}{
+		{NewResource("mybucket/%"), "mybucket/myobject", sql, true},
+		{NewResource("mybucket/%"), "mybucket/", sql, true},
+		{NewResource("mybucket/%.log"), "mybucket/2021/a.log", sql, true},
+		{NewResource("mybucket/%.log"), "mybucket/a.log.txt", sql, false},
+		{NewResource("mybucket/file_.txt"), "mybucket/file1.txt", sql, true},
+		{NewResource("mybucket/file_.txt"), "mybucket/file.txt", sql, false},
+		{NewResource("%"), "anybucket/anyobject", sql, true},
+		{NewResource("my%/photos/_"), "mybucket/photos/1", sql, true},
+		// '*' and '?' match literally.
+		{NewResource("mybucket/*"), "mybucket/myobject", sql, false},
+		{NewResource("mybucket/*"), "mybucket/*", sql, true},
+		{NewResource("mybucket/file?.txt"), "mybucket/file1.txt", sql, false},
+		{NewResource("mybucket/file?.txt"), "mybucket/file?.txt", sql, true},
+		// Only one wildcard replaced.
+		{NewResource("mybucket/%/file?.txt"), "mybucket/a/b/file1.txt", MatchOptions{MultiWildcard: '%'}, true},
+		{NewResource("mybucket/*/file_.txt"), "mybucket/a/b/file1.txt", MatchOptions{SingleWildcard: '_'}, true},
+		{NewResource("mybucket/*/file?.txt"), "mybucket/a/b/file1.txt", MatchOptions{SingleWildcard: '_'}, false},
+		// Default wildcards.
+		{NewResource("mybucket/%"), "mybucket/myobject", MatchOptions{}, false},
+		{NewResource("mybucket/*"), "mybucket/myobject", MatchOptions{SingleWildcard: '?', MultiWildcard: '*'}, true},
+		{NewResource("MyBucket/%"), "mybucket/myobject", MatchOptions{CaseInsensitiveBucket: true, MultiWildcard: '%'}, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.MatchWithOptions(testCase.objectName, nil, testCase.opts)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceMatchCollapseSlashes(t *testing.T) {
+	collapse, preserve := true, false
+
+	testCases := []struct {
+		resource       Resource
+		objectName     string
+		collapse       *bool
+		expectedResult bool
+	}{
+		// Consecutive slashes are collapsed by default.
+		{NewResource("mybucket/a/b"), "mybucket/a//b", nil, true},
+		{NewResource("mybucket/a/b"), "mybucket/a//b", &collapse, true},
+		{NewResource("mybucket/a/b"), "mybucket/a//b", &preserve, false},
+		{NewResource("mybucket/a/b"), "mybucket//a/b", &preserve, false},
+		{NewResource("mybucket/a/b"), "mybucket/a/./b", nil, true},
+		{NewResource("mybucket/a/b"), "mybucket/a/./b", &preserve, false},
+		{NewResource("mybucket/b"), "mybucket/a/../b", &preserve, false},
+		// Keys with literal slashes match exactly.
+		{NewResource("mybucket/a//b"), "mybucket/a//b", nil, true},
+		{NewResource("mybucket/a//b"), "mybucket/a//b", &preserve, true},
+		{NewResource("mybucket/a//b"), "mybucket/a/b", &preserve, false},
+		{NewResource("mybucket/a//b"), "mybucket/a/b", nil, false},
+		// Trailing slash is ignored in both modes.
+		{NewResource("mybucket"), "mybucket/", nil, true},
+		{NewResource("mybucket"), "mybucket/", &preserve, true},
+		{NewResource("mybucket"), "mybucket//", &preserve, false},
+		{NewResource("mybucket/a"), "mybucket/a/", &preserve, true},
+		// Wildcards match slashes in both modes.
+		{NewResource("mybucket/a/*"), "mybucket/a//b", &preserve, true},
+		{NewResource("mybucket/a?b"), "mybucket/a/b", &preserve, true},
+		{NewResource("mybucket/a/?/c"), "mybucket/a//c", &preserve, false},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.MatchWithOptions(testCase.objectName, nil, MatchOptions{CollapseSlashes: testCase.collapse})
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceMatchWithRecursionGuard(t *testing.T) {
+	job, err := parseResource("arn:aws:s3:us-east-1:111122223333:job/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	excessive := NewResource("mybucket/" + strings.Repeat("*a", 20) + "b")
+
+	testCases := []struct {
+		resource       Resource
+		objectName     string
+		limit          int
+		expectedResult bool
+		expectErr      bool
+	}{
+		{NewResource("mybucket/*"), "mybucket/myobject", 0, true, false},
+		{NewResource("mybucket/*.jpg"), "mybucket/photos/1.jpg", 0, true, false},
+		{NewResource("mybucket/*.jpg"), "mybucket/photos/1.png", 0, false, false},
+		{NewResource("mybucket/a/b"), "mybucket/a//b", 0, true, false},
+		{NewResource("mybucket/${aws:username}/*"), "mybucket/johndoe/a.txt", 0, true, false},
+		{job, "job/1234", 0, false, false},
+		{NewResource("mybucket/*.jpg"), "mybucket/photos/1.jpg", 2, false, true},
+		{excessive, "mybucket/" + strings.Repeat("a", 40), 0, false, true},
+		{excessive, "mybucket/" + strings.Repeat("a", 40), -1, false, true},
+		{excessive, "yourbucket/" + strings.Repeat("a", 40), 0, false, false},
+	}
+
+	conditionValues := map[string][]string{"username": {"johndoe"}}
+	for i, testCase := range testCases {
+		result, err := testCase.resource.MatchWithRecursionGuard(testCase.objectName, conditionValues, testCase.limit)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+		if expectErr {
+			if !errors.Is(err, wildcard.ErrMatchLimitExceeded) {
+				t.Fatalf("case %v: expected: %v, got: %v", i+1, wildcard.ErrMatchLimitExceeded, err)
+			}
+			continue
+		}
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+		if match := testCase.resource.Match(testCase.objectName, conditionValues); match != result {
+			t.Fatalf("case %v: expected Match to agree: %v, got: %v", i+1, match, result)
+		}
+	}
+}
+
+func TestResourceMatchRestrictVariableSlashes(t *testing.T) {
+	username := func(name string) map[string][]string {
+		return map[string][]string{"username": {name}, "userid": {"1234"}}
+	}
+
+	testCases := []struct {
+		resource        Resource
+		objectName      string
+		conditionValues map[string][]string
+		restrict        bool
+		expectedResult  bool
+	}{
+		{NewResource("mybucket/home/${aws:username}/*"), "mybucket/home/alice/a.txt", username("alice"), false, true},
+		{NewResource("mybucket/home/${aws:username}/*"), "mybucket/home/alice/a.txt", username("alice"), true, true},
+		// A crafted username reaches into the home of another user.
+		{NewResource("mybucket/home/${aws:username}/*"), "mybucket/home/alice/private/a.txt", username("alice/private"), false, true},
+		{NewResource("mybucket/home/${aws:username}/*"), "mybucket/home/alice/private/a.txt", username("alice/private"), true, false},
+		{NewResource("mybucket/home/${aws:username}"), "mybucket/home/alice/private", username("alice/private"), true, false},
+		{NewResource("mybucket/home/${aws:username}/*"), "mybucket/home/../admin/a.txt", username("../admin"), true, false},
+		// Only substituted variables are checked.
+		{NewResource("mybucket/home/${aws:userid}/*"), "mybucket/home/1234/a.txt", username("alice/private"), true, true},
+		{NewResource("mybucket/home/*"), "mybucket/home/alice/private/a.txt", username("alice/private"), true, true},
+		{NewResource("mybucket/home/${aws:username}/*"), "mybucket/home/${aws:username}/a.txt", nil, true, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.MatchWithOptions(testCase.objectName, testCase.conditionValues, MatchOptions{RestrictVariableSlashes: testCase.restrict})
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceMatchWildcardSlashes(t *testing.T) {
+	none := MatchOptions{}
+	multi := MatchOptions{MultiWildcardNoSlash: true}
+	single := MatchOptions{SingleWildcardNoSlash: true}
+	both := MatchOptions{SingleWildcardNoSlash: true, MultiWildcardNoSlash: true}
+
+	testCases := []struct {
+		resource   Resource
+		objectName string
+		// Expected results for none, multi, single and both.
+		expectedResults [4]bool
+	}{
+		{NewResource("mybucket/*"), "mybucket/a/b", [4]bool{true, false, true, false}},
+		{NewResource("mybucket/*"), "mybucket/a", [4]bool{true, true, true, true}},
+		{NewResource("mybucket/a?c"), "mybucket/a/c", [4]bool{true, true, false, false}},
+		{NewResource("mybucket/a?c"), "mybucket/abc", [4]bool{true, true, true, true}},
+		{NewResource("mybucket/*?c"), "mybucket/a/bc", [4]bool{true, false, true, false}},
+		{NewResource("mybucket/*?c"), "mybucket/ab/c", [4]bool{true, true, false, false}},
+		{NewResource("mybucket/*/?"), "mybucket/a/b", [4]bool{true, true, true, true}},
+		{NewResource("mybucket/*/?"), "mybucket/a/b/c", [4]bool{true, false, true, false}},
+		{NewResource("mybucket/*/?"), "mybucket/a//", [4]bool{true, true, false, false}},
+		{NewResource("my*/*"), "mybucket/a", [4]bool{true, true, true, true}},
+		{NewResource("*"), "mybucket/a", [4]bool{true, false, true, false}},
+		{NewResource("mybucket?a"), "mybucket/a", [4]bool{true, true, false, false}},
+		// Patterns without wildcards are not affected.
+		{NewResource("mybucket/a/b"), "mybucket/a/b", [4]bool{true, true, true, true}},
+		{NewResource("mybucket"), "mybucket/", [4]bool{true, true, true, true}},
+		{NewResource("mybucket/a/b"), "mybucket/a//b", [4]bool{true, true, true, true}},
+		// Policy variables are substituted first.
+		{NewResource("mybucket/${aws:username}/*"), "mybucket/johndoe/a", [4]bool{true, true, true, true}},
+		{NewResource("mybucket/${aws:username}/*"), "mybucket/johndoe/a/b", [4]bool{true, false, true, false}},
+	}
+
+	conditionValues := map[string][]string{"username": {"johndoe"}}
+	for i, testCase := range testCases {
+		for j, opts := range []MatchOptions{none, multi, single, both} {
+			result := testCase.resource.MatchWithOptions(testCase.objectName, conditionValues, opts)
+
+			if result != testCase.expectedResults[j] {
+				t.Fatalf("case %v: %+v: expected: %v, got: %v", i+1, opts, testCase.expectedResults[j], result)
+			}
+		}
+	}
+
+	// Options compose with custom wildcards and case folding.
+	opts := MatchOptions{SingleWildcard: '_', MultiWildcard: '%', MultiWildcardNoSlash: true, CaseInsensitiveBucket: true}
+	if !NewResource("MyBucket/%/_").MatchWithOptions("mybucket/a/b", nil, opts) {
+		t.Fatalf("expected match with custom wildcards")
+	}
+	if NewResource("MyBucket/%").MatchWithOptions("mybucket/a/b", nil, opts) {
+		t.Fatalf("expected no match with custom wildcards")
+	}
+}
+
+func TestResourceMatchStripLeadingSegments(t *testing.T) {
+	testCases := []struct {
+		resource       Resource
+		objectName     string
+		strip          int
+		expectedResult bool
+	}{
+		{NewResource("mybucket/photos/*"), "mybucket/tenant1/photos/1.jpg", 0, false},
+		{NewResource("mybucket/photos/*"), "mybucket/tenant1/photos/1.jpg", 1, true},
+		{NewResource("mybucket/photos/*"), "mybucket/tenant2/photos/1.jpg", 1, true},
+		{NewResource("mybucket/photos/*"), "mybucket/photos/1.jpg", 1, false},
+		{NewResource("mybucket/photos/*"), "mybucket/org/tenant1/photos/1.jpg", 2, true},
+		{NewResource("mybucket/photos/*"), "mybucket/org/tenant1/photos/1.jpg", 1, false},
+		{NewResource("mybucket/tenant1/*"), "mybucket/tenant1/a.txt", 1, false},
+		{NewResource("mybucket/a.txt"), "mybucket/tenant1/a.txt", 1, true},
+		{NewResource("mybucket/a.txt"), "mybucket/org/tenant1/a.txt", 2, true},
+		{NewResource("mybucket/a//b"), "mybucket/tenant1/a//b", 1, true},
+		// Objects without a remaining key never match.
+		{NewResource("mybucket/*"), "mybucket/tenant1", 1, false},
+		{NewResource("mybucket/*"), "mybucket/tenant1/", 1, false},
+		{NewResource("mybucket"), "mybucket/tenant1/", 1, false},
+		{NewResource("mybucket/*"), "mybucket/org/tenant1", 2, false},
+		// Buckets are matched as is.
+		{NewResource("mybucket"), "mybucket/", 1, true},
+		{NewResource("mybucket"), "mybucket", 2, true},
+		{NewResource("*"), "mybucket/tenant1/a.txt", 1, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.MatchWithOptions(testCase.objectName, nil, MatchOptions{StripLeadingSegments: testCase.strip})
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceWithAccount(t *testing.T) {
+	testCases := []struct {
+		resource       Resource
+		accountID      string
+		expectedResult string
+	}{
+		// Add account id.
+		{NewResource("mybucket/*"), "111122223333", "arn:aws:s3::111122223333:mybucket/*"},
+		// Replace account id.
+		{NewResource("mybucket/*").WithAccount("111122223333"), "444455556666", "arn:aws:s3::444455556666:mybucket/*"},
+		// Clear account id.
+		{NewResource("mybucket/*").WithAccount("111122223333"), "", "arn:aws:s3:::mybucket/*"},
+		{NewResource("mybucket"), "", "arn:aws:s3:::mybucket"},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.WithAccount(testCase.accountID)
+
+		if result.String() != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result.String())
+		}
+		if result.Pattern != testCase.resource.Pattern {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.resource.Pattern, result.Pattern)
+		}
+
+		var parsed Resource
+		data, err := json.Marshal(result)
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+		if err = json.Unmarshal(data, &parsed); err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+		if parsed != result {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, result, parsed)
+		}
+	}
+
+	if NewResource("mybucket/*").WithAccount("1234").IsValid() {
+		t.Fatalf("expected invalid account id to be rejected")
+	}
+	if !NewResource("mybucket/*").WithAccount("111122223333").MatchResource("mybucket/myobject") {
+		t.Fatalf("expected account id to be ignored while matching")
+	}
+}
+
+func TestResourceKind(t *testing.T) {
+	testCases := []struct {
+		arn            string
+		expectedResult ResourceKind
+	}{
+		{"arn:aws:s3:::mybucket", BucketResourceKind},
+		{"arn:aws:s3:::mybucket*", BucketResourceKind},
+		{"arn:aws:s3:::*", BucketResourceKind},
+		{"arn:aws:s3:::mybucket/*", ObjectResourceKind},
+		{"arn:aws:s3:::mybucket/myobject", ObjectResourceKind},
+		{"arn:aws:s3::111122223333:mybucket/myobject", ObjectResourceKind},
+		// Bucket named "job" without a region.
+		{"arn:aws:s3:::job/myjob", ObjectResourceKind},
+		{"arn:aws:s3:us-east-1:111122223333:job/myjob", JobResourceKind},
+		{"arn:aws:s3:us-east-1:111122223333:job/*", JobResourceKind},
+		{"arn:aws:s3:us-west-2:111122223333:storage-lens/mylens", StorageLensResourceKind},
+		{"arn:aws:s3:eu-west-1:111122223333:accesspoint/myap", AccessPointResourceKind},
+		{"arn:aws:s3:eu-west-1:111122223333:accesspoint/myap/object/photos/*", AccessPointResourceKind},
+	}
+
+	for i, testCase := range testCases {
+		resource, err := parseResource(testCase.arn)
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		if kind := resource.Kind(); kind != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, kind)
+		}
+
+		if resource.String() != testCase.arn {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.arn, resource.String())
+		}
+	}
+
+	if kind := (Resource{}).Kind(); kind != UnknownResourceKind {
+		t.Fatalf("expected: %v, got: %v", UnknownResourceKind, kind)
+	}
+	// Control-plane ARNs require an account.
+	if kind := (Resource{Pattern: "job/myjob", region: "us-east-1"}).Kind(); kind != ObjectResourceKind {
+		t.Fatalf("expected: %v, got: %v", ObjectResourceKind, kind)
+	}
+}
+
+func TestResourceMatchARN(t *testing.T) {
+	testCases := []struct {
+		resource       string
+		arn            string
+		expectedResult bool
+	}{
+		{"arn:aws:s3:us-east-1:111122223333:job/*", "arn:aws:s3:us-east-1:111122223333:job/myjob", true},
+		{"arn:aws:s3:us-east-1:111122223333:job/myjob", "arn:aws:s3:us-east-1:111122223333:job/myjob", true},
+		{"arn:aws:s3:us-east-1:111122223333:job/myjob", "arn:aws:s3:us-east-1:111122223333:job/otherjob", false},
+		{"arn:aws:s3:us-east-1:111122223333:job/*", "arn:aws:s3:us-west-2:111122223333:job/myjob", false},
+		{"arn:aws:s3:us-east-1:111122223333:job/*", "arn:aws:s3:us-east-1:444455556666:job/myjob", false},
+		{"arn:aws:s3:us-east-1:111122223333:job/*", "arn:aws:s3:us-east-1:111122223333:storage-lens/mylens", false},
+		{"arn:aws:s3:us-west-2:111122223333:storage-lens/*", "arn:aws:s3:us-west-2:111122223333:storage-lens/mylens", true},
+		{"arn:aws:s3:eu-west-1:111122223333:accesspoint/myap", "arn:aws:s3:eu-west-1:111122223333:accesspoint/myap", true},
+		{"arn:aws:s3:us-east-1:111122223333:job/*", "arn:aws:s3:::job/myjob", false},
+		{"arn:aws:s3:::job/*", "arn:aws:s3:us-east-1:111122223333:job/myjob", false},
+		{"arn:aws:s3:::mybucket/*", "arn:aws:s3:::mybucket/myobject", true},
+		{"arn:aws:s3:::mybucket/*", "arn:aws:s3:::otherbucket/myobject", false},
+		{"arn:aws:s3:::*", "mybucket/myobject", false},
+	}
+
+	for i, testCase := range testCases {
+		resource, err := parseResource(testCase.resource)
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		result := resource.MatchARN(testCase.arn)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+
+	// Control-plane resources never match bucket and object names.
+	job, err := parseResource("arn:aws:s3:us-east-1:111122223333:job/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if job.MatchResource("job/myjob") {
+		t.Fatalf("expected job resource not to match object name")
+	}
+	if NewResourceSet(job).Match("job/myjob", nil) {
+		t.Fatalf("expected job resource set not to match object name")
+	}
+	if re, err := NewResourceSet(job).ToRegexp(); err != nil || re.MatchString("job/myjob") {
+		t.Fatalf("expected job resource regexp not to match object name, err: %v", err)
+	}
+}
+
+func TestResourceValidateMaxWildcards(t *testing.T) {
+	testCases := []struct {
+		resource  Resource
+		n         int
+		expectErr bool
+	}{
+		{NewResource("mybucket/myobject"), 0, false},
+		{NewResource("mybucket/myobject?"), 0, false},
+		{NewResource("mybucket/${aws:username}"), 0, false},
+		{NewResource("mybucket/*"), 0, true},
+		{NewResource("mybucket/*"), 1, false},
+		{NewResource("mybucket/**"), 1, false},
+		{NewResource("*/photos/*.jpg"), 1, true},
+		{NewResource("*/photos/*.jpg"), 2, false},
+		{NewResource("*/*/*/*"), 3, true},
+		{NewResource("*/*/*/*"), 4, false},
+		{NewResource("*"), -1, true},
+	}
+
+	for i, testCase := range testCases {
+		err := testCase.resource.ValidateMaxWildcards(testCase.n)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+	}
+}
+
+func TestResourceCanonical(t *testing.T) {
+	testCases := []struct {
+		resource       Resource
+		expectedResult Resource
+	}{
+		{NewResource("mybucket"), NewResource("mybucket")},
+		{NewResource("mybucket/*"), NewResource("mybucket/*")},
+		{NewResource("mybucket/**"), NewResource("mybucket/*")},
+		{NewResource("**"), NewResource("*")},
+		{NewResource("*mybucket*"), NewResource("*mybucket*")},
+		{NewResource("mybucket/*?*.jpg"), NewResource("mybucket/?*.jpg")},
+		{NewResource("mybucket/${aws:username}/**"), NewResource("mybucket/${aws:username}/*")},
+		{NewResource("mybucket/**").WithAccount("111122223333"), NewResource("mybucket/*").WithAccount("111122223333")},
+	}
+
+	resources := []string{"mybucket", "mybucket/", "mybucket/a.jpg", "mybucket/.jpg", "mybucket/alice/x", "xmybucketx", ""}
+	for i, testCase := range testCases {
+		result := testCase.resource.Canonical()
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+
+		for _, resource := range resources {
+			if testCase.resource.MatchResource(resource) != result.MatchResource(resource) {
+				t.Fatalf("case %v: expected canonical resource to match %v the same", i+1, resource)
+			}
+		}
+	}
+}
+
+func TestResourceRegion(t *testing.T) {
+	testCases := []struct {
+		arn            string
+		expectedRegion string
+		expectedKind   ResourceKind
+		expectErr      bool
+	}{
+		{"arn:aws:s3:::mybucket", "", BucketResourceKind, false},
+		{"arn:aws:s3:::mybucket/*", "", ObjectResourceKind, false},
+		{"arn:aws:s3::111122223333:mybucket/*", "", ObjectResourceKind, false},
+		{"arn:aws:s3:us-east-1::mybucket", "us-east-1", BucketResourceKind, false},
+		{"arn:aws:s3:us-east-1::mybucket/key", "us-east-1", ObjectResourceKind, false},
+		{"arn:aws:s3:eu-west-2:111122223333:mybucket/a:b/c", "eu-west-2", ObjectResourceKind, false},
+		{"arn:aws:s3:us-gov-west-1::mybucket/*", "us-gov-west-1", ObjectResourceKind, false},
+		{"arn:aws:s3:ap-southeast-2:111122223333:job/myjob", "ap-southeast-2", JobResourceKind, false},
+		{"arn:aws:s3:US-East-1::mybucket/key", "", UnknownResourceKind, true},
+		{"arn:aws:s3:us-east::mybucket/key", "", UnknownResourceKind, true},
+		{"arn:aws:s3:useast1::mybucket/key", "", UnknownResourceKind, true},
+		{"arn:aws:s3:us-east-1-::mybucket/key", "", UnknownResourceKind, true},
+		{"arn:aws:s3:*::mybucket/key", "", UnknownResourceKind, true},
+		{"arn:aws:s3:us-east-1::/key", "", UnknownResourceKind, true},
+	}
+
+	for i, testCase := range testCases {
+		resource, err := parseResource(testCase.arn)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+
+		if testCase.expectErr {
+			continue
+		}
+
+		if resource.Region() != testCase.expectedRegion {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedRegion, resource.Region())
+		}
+
+		if resource.Kind() != testCase.expectedKind {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedKind, resource.Kind())
+		}
+
+		if !resource.IsValid() {
+			t.Fatalf("case %v: expected resource to be valid", i+1)
+		}
+
+		// String must round-trip through JSON.
+		if resource.String() != testCase.arn {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.arn, resource.String())
+		}
+
+		data, err := json.Marshal(resource)
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		var parsed Resource
+		if err = json.Unmarshal(data, &parsed); err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		if parsed != resource {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, resource, parsed)
+		}
+	}
+
+	// The region is not matched.
+	resource, err := parseResource("arn:aws:s3:us-east-1::mybucket/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resource.MatchResource("mybucket/myobject") {
+		t.Fatalf("expected region to be ignored while matching")
+	}
+	if (Resource{Pattern: "mybucket", region: "us-east"}).IsValid() {
+		t.Fatalf("expected invalid region to be rejected")
+	}
+}
+
+func TestParseResource(t *testing.T) {
+	testCases := []struct {
+		s              string
+		opts           ParseOptions
+		expectedResult Resource
+		expectErr      bool
+	}{
+		{"arn:aws:s3:::mybucket/*", ParseOptions{}, NewResource("mybucket/*"), false},
+		{"arn:aws:s3:::mybucket/*", ParseOptions{LenientARNPrefix: true}, NewResource("mybucket/*"), false},
+		{"ARN:AWS:S3:::mybucket/*", ParseOptions{}, Resource{}, true},
+		{"ARN:AWS:S3:::mybucket/*", ParseOptions{LenientARNPrefix: true}, NewResource("mybucket/*"), false},
+		{"Arn:Aws:s3:::MyBucket/Key", ParseOptions{LenientARNPrefix: true}, NewResource("MyBucket/Key"), false},
+		{"ARN:AWS:S3::111122223333:mybucket/*", ParseOptions{LenientARNPrefix: true}, NewResource("mybucket/*").WithAccount("111122223333"), false},
+		{"ARN:AWS:S3:US-EAST-1::mybucket/*", ParseOptions{LenientARNPrefix: true}, Resource{}, true},
+		{"ARN:AWS:S3", ParseOptions{LenientARNPrefix: true}, Resource{}, true},
+		{"mybucket/*", ParseOptions{LenientARNPrefix: true}, Resource{}, true},
+	}
+
+	for i, testCase := range testCases {
+		result, err := ParseResource(testCase.s, testCase.opts)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+
+		if !testCase.expectErr && result != testCase.expectedResult {
+			t.Fatalf("case %v: result: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceMatchObject(t *testing.T) {
+	testCases := []struct {
+		resource        Resource
+		bucket          string
+		object          string
+		conditionValues map[string][]string
+		expectedResult  bool
+	}{
+		{NewResource("mybucket/*"), "mybucket", "myobject", nil, true},
+		{NewResource("mybucket/*"), "mybucket", "a/b/c", nil, true},
+		{NewResource("mybucket/myobject"), "mybucket", "myobject", nil, true},
+		{NewResource("mybucket/myobject"), "mybucket", "/myobject", nil, true},
+		// Cleaned to "mybucket/myobject" for exact matches.
+		{NewResource("mybucket/myobject"), "mybucket", "//myobject", nil, true},
+		{NewResource("mybucket/*"), "mybucket", "//myobject", nil, true},
+		{NewResource("mybucket/myobject"), "yourbucket", "myobject", nil, false},
+		// Empty object refers to the bucket.
+		{NewResource("mybucket"), "mybucket", "", nil, true},
+		{NewResource("mybucket/*"), "mybucket", "", nil, true},
+		{NewResource("mybucket/myobject"), "mybucket", "", nil, false},
+		{NewResource("mybucket"), "mybucket", "myobject", nil, false},
+		{NewResource("mybucket/${aws:username}/*"), "mybucket", "alice/a.txt", map[string][]string{"username": {"alice"}}, true},
+		{NewResource("mybucket/${aws:username}/*"), "mybucket", "/alice/a.txt", map[string][]string{"username": {"alice"}}, true},
+		{NewResource("mybucket/${aws:username}/*"), "mybucket", "bob/a.txt", map[string][]string{"username": {"alice"}}, false},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.MatchObject(testCase.bucket, testCase.object, testCase.conditionValues)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+
+		// Statements join bucket and object the same way.
+		statement := NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(testCase.resource), condition.NewFunctions())
+		allowed := statement.IsAllowed(Args{
+			Action:          GetObjectAction,
+			BucketName:      testCase.bucket,
+			ObjectName:      testCase.object,
+			ConditionValues: testCase.conditionValues,
+		})
+		if allowed != result {
+			t.Fatalf("case %v: expected statement to agree: %v, got: %v", i+1, result, allowed)
+		}
+	}
+}
+
+func TestResourceCanonicalARN(t *testing.T) {
+	testCases := []struct {
+		resources      []string
+		expectedResult string
+	}{
+		{[]string{"arn:aws:s3:::mybucket"}, "arn:aws:s3:::mybucket"},
+		{[]string{"arn:aws:s3:::mybucket/*", "arn:aws:s3:::mybucket/**", "arn:aws:s3:::mybucket//*", "arn:aws:s3:::mybucket///***"}, "arn:aws:s3:::mybucket/*"},
+		{[]string{"arn:aws:s3:::mybucket/a/b", "arn:aws:s3:::mybucket//a//b", "arn:aws:s3:::mybucket/a///b"}, "arn:aws:s3:::mybucket/a/b"},
+		{[]string{"arn:aws:s3:::mybucket/?*.jpg", "arn:aws:s3:::mybucket/*?.jpg", "arn:aws:s3:::mybucket/*?**.jpg"}, "arn:aws:s3:::mybucket/?*.jpg"},
+		{[]string{"arn:aws:s3:::mybucket/${aws:username}/*", "arn:aws:s3:::mybucket//${aws:username}//**"}, "arn:aws:s3:::mybucket/${aws:username}/*"},
+		{[]string{"arn:aws:s3:::mybucket/", "arn:aws:s3:::mybucket//"}, "arn:aws:s3:::mybucket/"},
+		{[]string{"arn:aws:s3::111122223333:mybucket//a/**"}, "arn:aws:s3::111122223333:mybucket/a/*"},
+		{[]string{"arn:aws:s3:us-east-1:111122223333:job//**"}, "arn:aws:s3:us-east-1:111122223333:job/*"},
+		// Different patterns keep different canonical ARNs.
+		{[]string{"arn:aws:s3:::mybucket?"}, "arn:aws:s3:::mybucket?"},
+		{[]string{"arn:aws:s3:::*mybucket*"}, "arn:aws:s3:::*mybucket*"},
+	}
+
+	for i, testCase := range testCases {
+		for _, s := range testCase.resources {
+			resource, err := parseResource(s)
+			if err != nil {
+				t.Fatalf("case %v: unexpected error: %v", i+1, err)
+			}
+
+			result := resource.CanonicalARN()
+			if result != testCase.expectedResult {
+				t.Fatalf("case %v: %v: expected: %v, got: %v", i+1, s, testCase.expectedResult, result)
+			}
+
+			// The canonical ARN is a fixed point.
+			canonical, err := parseResource(result)
+			if err != nil {
+				t.Fatalf("case %v: unexpected error: %v", i+1, err)
+			}
+			if canonical.CanonicalARN() != result {
+				t.Fatalf("case %v: expected: %v, got: %v", i+1, result, canonical.CanonicalARN())
+			}
+		}
+	}
+}
+
+func TestSetPartition(t *testing.T) {
+	defer func() {
+		if err := SetPartition(DefaultPartition); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}()
+
+	if Partition() != DefaultPartition {
+		t.Fatalf("expected: %v, got: %v", DefaultPartition, Partition())
+	}
+	if ARNPrefix() != ResourceARNPrefix {
+		t.Fatalf("expected: %v, got: %v", ResourceARNPrefix, ARNPrefix())
+	}
+
+	for _, partition := range []string{"", "AWS", "custom partition", "custom:partition", "-custom", "custom-"} {
+		if err := SetPartition(partition); err == nil {
+			t.Fatalf("%v: expected error", partition)
+		}
+	}
+
+	if err := SetPartition("custom-partition"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if Partition() != "custom-partition" {
+		t.Fatalf("expected: %v, got: %v", "custom-partition", Partition())
+	}
+	if ARNPrefix() != "arn:custom-partition:s3:::" {
+		t.Fatalf("expected: %v, got: %v", "arn:custom-partition:s3:::", ARNPrefix())
+	}
+	if ResourceARNPrefix != "arn:aws:s3:::" {
+		t.Fatalf("expected: %v, got: %v", "arn:aws:s3:::", ResourceARNPrefix)
+	}
+	if _, err := parseResource(NewResource("mybucket").String()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		s         string
+		expectErr bool
+	}{
+		{"arn:custom-partition:s3:::mybucket", false},
+		{"arn:custom-partition:s3:::mybucket/photos/*", false},
+		{"arn:custom-partition:s3::111122223333:mybucket/*", false},
+		{"arn:custom-partition:s3:us-east-1:111122223333:job/*", false},
+		{"arn:aws:s3:::mybucket/*", true},
+		{"arn:custom:s3:::mybucket/*", true},
+	}
+
+	for i, testCase := range testCases {
+		resource, err := parseResource(testCase.s)
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+		if expectErr {
+			continue
+		}
+		if resource.String() != testCase.s {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.s, resource.String())
+		}
+	}
+
+	p, err := ParseConfig(strings.NewReader(`{
+    "Version": "2012-10-17",
+    "Statement": [{"Effect": "Allow", "Action": "s3:GetObject", "Resource": "arn:custom-partition:s3:::mybucket/*"}]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.IsAllowed(Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "a.txt"}) {
+		t.Fatalf("expected allowed")
+	}
+
+	data, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), `"arn:custom-partition:s3:::mybucket/*"`) {
+		t.Fatalf("expected custom partition ARN in %s", data)
+	}
+	q, err := ParseConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !q.Equals(*p) {
+		t.Fatalf("expected: %v, got: %v", p, q)
+	}
+}
+
+func TestResourceAllowsDelimitedListing(t *testing.T) {
+	job, err := parseResource("arn:aws:s3:us-east-1:111122223333:job/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		resource       Resource
+		prefix         string
+		delimiter      string
+		expectedResult bool
+	}{
+		// Listings within the pattern.
+		{NewResource("mybucket/home/alice/*"), "home/alice/", "/", true},
+		{NewResource("mybucket/home/alice/*"), "home/alice/", "", true},
+		{NewResource("mybucket/home/alice/*"), "home/alice/docs/", "/", true},
+		{NewResource("mybucket/home/alice/*"), "home/alice/do", "/", true},
+		{NewResource("mybucket/*"), "", "", true},
+		{NewResource("mybucket/*"), "home/", "/", true},
+		// Navigating to the pattern with a delimiter.
+		{NewResource("mybucket/home/alice/*"), "", "/", true},
+		{NewResource("mybucket/home/alice/*"), "home/", "/", true},
+		{NewResource("mybucket/home/alice/docs/*.txt"), "home/alice/", "/", true},
+		{NewResource("mybucket/photos/*.jpg"), "photos/", "/", true},
+		{NewResource("mybucket/home/${aws:username}/*"), "home/", "/", true},
+		// Listings exposing other objects.
+		{NewResource("mybucket/home/alice/*"), "", "", false},
+		{NewResource("mybucket/home/alice/*"), "home/", "", false},
+		{NewResource("mybucket/home/alice/*"), "home/al", "/", false},
+		{NewResource("mybucket/home/alice/*"), "home/bob/", "/", false},
+		{NewResource("mybucket/home/alice/*"), "home", "/", false},
+		{NewResource("mybucket/home/alice/*"), "home/", "-", false},
+		{NewResource("mybucket/photos/*.jpg"), "photos/", "", false},
+		{NewResource("mybucket/photos/*.jpg"), "photos/2021/", "/", false},
+		{NewResource("mybucket/home/${aws:username}/*"), "home/alice/", "/", false},
+		// Bucket and control-plane resources allow no listing.
+		{NewResource("mybucket"), "", "/", false},
+		{NewResource("*"), "", "/", false},
+		{job, "", "/", false},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resource.AllowsDelimitedListing(testCase.prefix, testCase.delimiter)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceDiffMatch(t *testing.T) {
+	testCases := []struct {
+		resource          Resource
+		other             Resource
+		expectedOnlyR     []string
+		expectedOnlyOther []string
+	}{
+		{NewResource("bucket/*"), NewResource("bucket/logs/*"), []string{"bucket/", "bucket//", "bucket/a", "bucket/a/a"}, []string{}},
+		{NewResource("bucket/logs/*"), NewResource("bucket/*"), []string{}, []string{"bucket/", "bucket//", "bucket/a", "bucket/a/a"}},
+		{NewResource("bucket/*.jpg"), NewResource("bucket/?.jpg"), []string{"bucket/.jpg", "bucket/a/a.jpg"}, []string{}},
+		{NewResource("bucket/a?"), NewResource("bucket/?b"), []string{"bucket/a/", "bucket/aa"}, []string{"bucket//b"}},
+		{NewResource("bucket/*"), NewResource("bucket/**"), []string{}, []string{}},
+		{NewResource("bucket/logs"), NewResource("bucket/logs"), []string{}, []string{}},
+	}
+
+	for i, testCase := range testCases {
+		onlyR, onlyOther := testCase.resource.DiffMatch(testCase.other)
+
+		if !reflect.DeepEqual(onlyR, testCase.expectedOnlyR) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedOnlyR, onlyR)
+		}
+		if !reflect.DeepEqual(onlyOther, testCase.expectedOnlyOther) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedOnlyOther, onlyOther)
+		}
+		for _, example := range onlyR {
+			if !testCase.resource.MatchResource(example) || testCase.other.MatchResource(example) {
+				t.Fatalf("case %v: %v is not matched only by %v", i+1, example, testCase.resource)
+			}
+		}
+	}
+
+	// Examples are bounded.
+	onlyR, _ := NewResource("*/*/*/*").DiffMatch(NewResource("nothing"))
+	if len(onlyR) != DiffMatchExamples {
+		t.Fatalf("expected: %v, got: %v", DiffMatchExamples, len(onlyR))
+	}
+}
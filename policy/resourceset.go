@@ -18,11 +18,16 @@
 package policy
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
+	"io"
+	"regexp"
 	"sort"
+	"strings"
 
 	"github.com/trinet2005/oss-go-sdk/pkg/set"
+	"github.com/trinet2005/oss-pkg/wildcard"
 )
 
 // ResourceSet - set of resources in policy statement.
@@ -120,6 +125,89 @@ func (resourceSet ResourceSet) Match(resource string, conditionValues map[string
 	return false
 }
 
+// CompletionsFor - returns resources, sorted by their ARN, whose pattern
+// could match a resource having partial as a prefix, i.e. partial is
+// consistent with the pattern up to its length or up to the first '*' of
+// the pattern. A '?' of the pattern is consistent with any character and
+// policy variables are treated as '*'. For example "mybucket/ph" is
+// consistent with "mybucket/photos/*", "mybucket*" and "*" but not with
+// "mybucket/videos/*" or "mybucket".
+func (resourceSet ResourceSet) CompletionsFor(partial string) []Resource {
+	resources := []Resource{}
+	for _, resource := range resourceSet.sortedResources() {
+		if !resource.matchable() {
+			continue
+		}
+
+		pattern := resource.Pattern
+		for _, v := range policyVariables(pattern) {
+			pattern = strings.ReplaceAll(pattern, v, "*")
+		}
+
+		if wildcard.MatchAsPatternPrefix(pattern, partial) {
+			resources = append(resources, resource)
+		}
+	}
+
+	return resources
+}
+
+// redundantResources - returns resources, sorted by their ARN, whose pattern
+// is contained in the pattern of another resource of the set, see
+// wildcard.MatchPattern. Of resources with equivalent patterns, e.g.
+// "mybucket/*" and "mybucket/**", all but the first in ARN order are
+// redundant.
+func (resourceSet ResourceSet) redundantResources() []Resource {
+	resources := resourceSet.sortedResources()
+
+	redundant := []Resource{}
+	for i, resource := range resources {
+		if !resource.matchable() {
+			continue
+		}
+
+		for j, other := range resources {
+			if i == j || !other.matchable() || !wildcard.MatchPattern(other.Pattern, resource.Pattern) {
+				continue
+			}
+			if j > i && wildcard.MatchPattern(resource.Pattern, other.Pattern) {
+				// Equivalent patterns, the first one is kept.
+				continue
+			}
+			redundant = append(redundant, resource)
+			break
+		}
+	}
+
+	return redundant
+}
+
+// ToRegexp - returns an anchored regular expression matching the same
+// resources as MatchResource for clean resource names, i.e. names equal to
+// their path.Clean form. MatchResource also matches a name whose cleaned
+// form equals a pattern, e.g. "mybucket/" for pattern "mybucket" or "a//b"
+// for pattern "a/b", which the regular expression does not match as
+// '.' and '..' elements cannot be expressed by it. Hence a match of the
+// regular expression always implies a match of MatchResource, but not the
+// other way around for unclean names.
+func (resourceSet ResourceSet) ToRegexp() (*regexp.Regexp, error) {
+	exprs := []string{}
+	for resource := range resourceSet {
+		if !resource.matchable() {
+			continue
+		}
+		exprs = append(exprs, resource.regexpString())
+	}
+
+	if len(exprs) == 0 {
+		// Matches nothing, the same as an empty resource set.
+		return regexp.Compile(`[^\x00-\x{10FFFF}]`)
+	}
+	sort.Strings(exprs)
+
+	return regexp.Compile(`^(?s:` + strings.Join(exprs, "|") + `)$`)
+}
+
 func (resourceSet ResourceSet) String() string {
 	resources := []string{}
 	for resource := range resourceSet {
@@ -154,6 +242,73 @@ func (resourceSet *ResourceSet) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// WriteLines - writes resources to w, one ARN per line in sorted order.
+func (resourceSet ResourceSet) WriteLines(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, resource := range resourceSet.sortedResources() {
+		if _, err := bw.WriteString(resource.String() + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// Walk - calls fn for each resource in the order of their ARN, until fn
+// returns false.
+func (resourceSet ResourceSet) Walk(fn func(Resource) bool) {
+	for _, resource := range resourceSet.sortedResources() {
+		if !fn(resource) {
+			return
+		}
+	}
+}
+
+// sortedResources - returns resources sorted by their ARN.
+func (resourceSet ResourceSet) sortedResources() []Resource {
+	resources := make([]Resource, 0, len(resourceSet))
+	for resource := range resourceSet {
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].String() < resources[j].String()
+	})
+
+	return resources
+}
+
+// ReadResourceSet - reads resources written by WriteLines from r, one ARN
+// per line. Blank lines and lines starting with '#', after any leading
+// white space, are skipped. Other lines are parsed as is without a trailing
+// '\r', as resource patterns may start or end with white space.
+func ReadResourceSet(r io.Reader) (ResourceSet, error) {
+	resourceSet := NewResourceSet()
+
+	// Lines are split by bufio.ScanLines, which drops a trailing '\r'.
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := scanner.Text()
+		if trimmed := strings.TrimSpace(s); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		resource, err := parseResource(s)
+		if err != nil {
+			return nil, Errorf("line %d: %w", line, err)
+		}
+
+		if _, found := resourceSet[resource]; found {
+			return nil, Errorf("line %d: duplicate resource '%v' found", line, s)
+		}
+
+		resourceSet.Add(resource)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return resourceSet, nil
+}
+
 // Validate - validates ResourceSet.
 func (resourceSet ResourceSet) Validate() error {
 	for resource := range resourceSet {
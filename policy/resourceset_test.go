@@ -18,8 +18,12 @@
 package policy
 
 import (
+	"bytes"
 	"encoding/json"
+	"math/rand"
+	"path"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -271,3 +275,303 @@ func TestResourceSetValidateBucket(t *testing.T) {
 		}
 	}
 }
+
+func TestResourceSetToRegexp(t *testing.T) {
+	testCases := []struct {
+		resourceSet    ResourceSet
+		resource       string
+		expectedResult bool
+	}{
+		{NewResourceSet(NewResource("*")), "mybucket/myobject", true},
+		{NewResourceSet(NewResource("mybucket?0/2010/photos/*")), "mybucket20/2010/photos/1.jpg", true},
+		{NewResourceSet(NewResource("mybucket/*"), NewResource("other")), "other", true},
+		{NewResourceSet(NewResource("my.bucket/(a)+")), "my.bucket/(a)+", true},
+		{NewResourceSet(NewResource("my.bucket/(a)+")), "myxbucket/aa", false},
+		{NewResourceSet(NewResource("mybucket/*")), "mybucket10/myobject", false},
+		{NewResourceSet(NewResource("mybucket?0")), "mybucket0", false},
+		{NewResourceSet(), "mybucket/myobject", false},
+		{NewResourceSet(), "", false},
+		// Unclean names only matching after path cleaning.
+		{NewResourceSet(NewResource("mybucket")), "mybucket/", false},
+		{NewResourceSet(NewResource("a/b")), "a//b", false},
+		{NewResourceSet(NewResource("a/*")), "a//b", true},
+	}
+
+	for i, testCase := range testCases {
+		re, err := testCase.resourceSet.ToRegexp()
+		if err != nil {
+			t.Fatalf("case %v: unexpected error: %v", i+1, err)
+		}
+
+		result := re.MatchString(testCase.resource)
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceSetToRegexpMatchesMatchResource(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	randomSegment := func(alphabet string) string {
+		b := make([]byte, 1+rnd.Intn(3))
+		for j := range b {
+			b[j] = alphabet[rnd.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+	randomPath := func(alphabet string) string {
+		segments := make([]string, 1+rnd.Intn(3))
+		for i := range segments {
+			for segments[i] == "" || segments[i] == "." || segments[i] == ".." {
+				segments[i] = randomSegment(alphabet)
+			}
+		}
+		return strings.Join(segments, "/")
+	}
+	// randomName - returns a resource name which may be unclean, i.e. with
+	// empty, '.' or '..' elements or a trailing '/'.
+	randomName := func(alphabet string) string {
+		segments := make([]string, 1+rnd.Intn(3))
+		for i := range segments {
+			switch rnd.Intn(6) {
+			case 0:
+				segments[i] = ""
+			case 1:
+				segments[i] = "."
+			case 2:
+				segments[i] = ".."
+			default:
+				segments[i] = randomSegment(alphabet)
+			}
+		}
+		if segments[0] == "" {
+			segments[0] = randomSegment(alphabet)
+		}
+		name := strings.Join(segments, "/")
+		if rnd.Intn(4) == 0 {
+			name += "/"
+		}
+		return name
+	}
+
+	for i := 0; i < 2000; i++ {
+		resourceSet := NewResourceSet()
+		for j := rnd.Intn(3); j >= 0; j-- {
+			resourceSet.Add(NewResource(randomPath("ab.+*?")))
+		}
+		re, err := resourceSet.ToRegexp()
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", resourceSet, err)
+		}
+
+		for j := 0; j < 20; j++ {
+			resource := randomPath("ab.+*?")
+			if expected, got := resourceSet.MatchResource(resource), re.MatchString(resource); expected != got {
+				t.Fatalf("%v: %v: expected: %v, got: %v", resourceSet, resource, expected, got)
+			}
+		}
+
+		// For unclean names a match of the regular expression implies a
+		// match of MatchResource, and both agree on clean names.
+		for j := 0; j < 20; j++ {
+			resource := randomName("ab.+*?")
+			expected, got := resourceSet.MatchResource(resource), re.MatchString(resource)
+			if path.Clean(resource) == resource && expected != got {
+				t.Fatalf("%v: %v: expected: %v, got: %v", resourceSet, resource, expected, got)
+			}
+			if got && !expected {
+				t.Fatalf("%v: %v: expected: %v, got: %v", resourceSet, resource, expected, got)
+			}
+		}
+	}
+}
+
+func TestResourceSetWriteLines(t *testing.T) {
+	resourceSet := NewResourceSet(
+		NewResource("mybucket/*"),
+		NewResource("mybucket"),
+		NewResource("*"),
+		NewResource("mybucket/a:b/c").WithAccount("111122223333"),
+	)
+	expected := `arn:aws:s3::111122223333:mybucket/a:b/c
+arn:aws:s3:::*
+arn:aws:s3:::mybucket
+arn:aws:s3:::mybucket/*
+`
+
+	var buf bytes.Buffer
+	if err := resourceSet.WriteLines(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != expected {
+		t.Fatalf("expected: %v, got: %v", expected, buf.String())
+	}
+
+	result, err := ReadResourceSet(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result, resourceSet) {
+		t.Fatalf("expected: %v, got: %v", resourceSet, result)
+	}
+}
+
+func TestResourceSetWriteLinesWhiteSpace(t *testing.T) {
+	resourceSet := NewResourceSet(NewResource("mybucket/a "), NewResource("mybucket/\tb"))
+
+	var buf bytes.Buffer
+	if err := resourceSet.WriteLines(&buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := ReadResourceSet(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result, resourceSet) {
+		t.Fatalf("expected: %v, got: %v", resourceSet, result)
+	}
+}
+
+func TestReadResourceSet(t *testing.T) {
+	testCases := []struct {
+		data           string
+		expectedResult ResourceSet
+		expectErr      bool
+	}{
+		{`# resources of mybucket
+arn:aws:s3:::mybucket
+
+  # objects
+arn:aws:s3:::mybucket/*
+`, NewResourceSet(NewResource("mybucket"), NewResource("mybucket/*")), false},
+		{"arn:aws:s3:::mybucket\r\narn:aws:s3:::mybucket/*", NewResourceSet(NewResource("mybucket"), NewResource("mybucket/*")), false},
+		// White space is part of patterns.
+		{"arn:aws:s3:::mybucket/a \r\narn:aws:s3:::mybucket/ b\n", NewResourceSet(NewResource("mybucket/a "), NewResource("mybucket/ b")), false},
+		{"  arn:aws:s3:::mybucket\n", nil, true},
+		{"", NewResourceSet(), false},
+		{"# only comments\n\n", NewResourceSet(), false},
+		{"arn:aws:s3:::mybucket\nmybucket/*\n", nil, true},
+		{"arn:aws:s3:::mybucket\narn:aws:s3:::mybucket\n", nil, true},
+	}
+
+	for i, testCase := range testCases {
+		result, err := ReadResourceSet(strings.NewReader(testCase.data))
+		expectErr := (err != nil)
+
+		if expectErr != testCase.expectErr {
+			t.Fatalf("case %v: error: expected: %v, got: %v", i+1, testCase.expectErr, expectErr)
+		}
+
+		if !testCase.expectErr {
+			if !reflect.DeepEqual(result, testCase.expectedResult) {
+				t.Fatalf("case %v: result: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+			}
+		}
+	}
+}
+
+func TestResourceSetCompletionsFor(t *testing.T) {
+	job, err := parseResource("arn:aws:s3:us-east-1:111122223333:job/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resourceSet := NewResourceSet(
+		NewResource("mybucket"),
+		NewResource("mybucket/photos/*"),
+		NewResource("mybucket/videos/*"),
+		NewResource("mybucket/20??/report.csv"),
+		NewResource("mybucket/home/${aws:username}/*"),
+		NewResource("otherbucket*"),
+		job,
+	)
+
+	testCases := []struct {
+		resourceSet    ResourceSet
+		partial        string
+		expectedResult []Resource
+	}{
+		{resourceSet, "", []Resource{
+			NewResource("mybucket"),
+			NewResource("mybucket/20??/report.csv"),
+			NewResource("mybucket/home/${aws:username}/*"),
+			NewResource("mybucket/photos/*"),
+			NewResource("mybucket/videos/*"),
+			NewResource("otherbucket*"),
+		}},
+		{resourceSet, "my", []Resource{
+			NewResource("mybucket"),
+			NewResource("mybucket/20??/report.csv"),
+			NewResource("mybucket/home/${aws:username}/*"),
+			NewResource("mybucket/photos/*"),
+			NewResource("mybucket/videos/*"),
+		}},
+		{resourceSet, "mybucket", []Resource{
+			NewResource("mybucket"),
+			NewResource("mybucket/20??/report.csv"),
+			NewResource("mybucket/home/${aws:username}/*"),
+			NewResource("mybucket/photos/*"),
+			NewResource("mybucket/videos/*"),
+		}},
+		{resourceSet, "mybucket/ph", []Resource{NewResource("mybucket/photos/*")}},
+		{resourceSet, "mybucket/photos/2021/a.jpg", []Resource{NewResource("mybucket/photos/*")}},
+		{resourceSet, "mybucket/2021/rep", []Resource{NewResource("mybucket/20??/report.csv")}},
+		{resourceSet, "mybucket/2021/report.csv.gz", []Resource{}},
+		{resourceSet, "mybucket/home/alice/docs", []Resource{NewResource("mybucket/home/${aws:username}/*")}},
+		{resourceSet, "otherbucket-2/x", []Resource{NewResource("otherbucket*")}},
+		{resourceSet, "job/", []Resource{}},
+		{resourceSet, "yourbucket", []Resource{}},
+		{NewResourceSet(NewResource("*")), "anything/at/all", []Resource{NewResource("*")}},
+		{NewResourceSet(), "mybucket", []Resource{}},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.resourceSet.CompletionsFor(testCase.partial)
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestResourceSetWalk(t *testing.T) {
+	resourceSet := NewResourceSet(
+		NewResource("mybucket/*"),
+		NewResource("mybucket"),
+		NewResource("*"),
+		NewResource("yourbucket/*"),
+	)
+
+	testCases := []struct {
+		resourceSet    ResourceSet
+		stopAfter      int
+		expectedResult []Resource
+	}{
+		{resourceSet, -1, []Resource{
+			NewResource("*"),
+			NewResource("mybucket"),
+			NewResource("mybucket/*"),
+			NewResource("yourbucket/*"),
+		}},
+		{resourceSet, 1, []Resource{NewResource("*")}},
+		{resourceSet, 3, []Resource{
+			NewResource("*"),
+			NewResource("mybucket"),
+			NewResource("mybucket/*"),
+		}},
+		{NewResourceSet(), -1, nil},
+	}
+
+	for i, testCase := range testCases {
+		var result []Resource
+		testCase.resourceSet.Walk(func(resource Resource) bool {
+			result = append(result, resource)
+			return len(result) != testCase.stopAfter
+		})
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
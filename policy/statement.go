@@ -18,6 +18,7 @@
 package policy
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/trinet2005/oss-pkg/policy/condition"
@@ -27,7 +28,7 @@ import (
 type Statement struct {
 	SID        ID                  `json:"Sid,omitempty"`
 	Effect     Effect              `json:"Effect"`
-	Actions    ActionSet           `json:"Action"`
+	Actions    ActionSet           `json:"Action,omitempty"`
 	NotActions ActionSet           `json:"NotAction,omitempty"`
 	Resources  ResourceSet         `json:"Resource,omitempty"`
 	Conditions condition.Functions `json:"Condition,omitempty"`
@@ -35,25 +36,21 @@ type Statement struct {
 
 // IsAllowed - checks given policy args is allowed to continue the Rest API.
 func (statement Statement) IsAllowed(args Args) bool {
+	if args.Anonymous {
+		if statement.Effect == Allow && statement.usesIdentityKeys() {
+			return false
+		}
+		args = anonymousArgs(args)
+	}
+
 	check := func() bool {
 		if (!statement.Actions.Match(args.Action) && !statement.Actions.IsEmpty()) ||
 			statement.NotActions.Match(args.Action) {
 			return false
 		}
 
-		resource := args.BucketName
-		if args.ObjectName != "" {
-			if !strings.HasPrefix(args.ObjectName, "/") {
-				resource += "/"
-			}
-
-			resource += args.ObjectName
-		} else {
-			resource += "/"
-		}
-
 		// For admin statements, resource match can be ignored.
-		if !statement.Resources.Match(resource, args.ConditionValues) && !statement.isAdmin() && !statement.isKMS() {
+		if !statement.Resources.Match(argsResource(args), args.ConditionValues) && !statement.isAdmin() && !statement.isKMS() {
 			return false
 		}
 
@@ -63,6 +60,173 @@ func (statement Statement) IsAllowed(args Args) bool {
 	return statement.Effect.IsAllowed(check())
 }
 
+// WhyNotAllowed - returns human readable reasons why the statement does not
+// allow given policy args, or nil if it does. For an Allow statement every
+// mismatching action, resource and unsatisfied condition is reported. A
+// Deny statement does not allow args it matches.
+func (statement Statement) WhyNotAllowed(args Args) []string {
+	if statement.IsAllowed(args) {
+		return nil
+	}
+
+	if statement.Effect != Allow {
+		return []string{fmt.Sprintf("action '%v' on resource '%v' is explicitly denied", args.Action, argsResource(args))}
+	}
+
+	var reasons []string
+	if !statement.Actions.Match(args.Action) && !statement.Actions.IsEmpty() {
+		reasons = append(reasons, fmt.Sprintf("action '%v' does not match Action %v", args.Action, statement.Actions))
+	}
+	if statement.NotActions.Match(args.Action) {
+		reasons = append(reasons, fmt.Sprintf("action '%v' matches NotAction %v", args.Action, statement.NotActions))
+	}
+
+	resource := argsResource(args)
+	if !statement.Resources.Match(resource, args.ConditionValues) && !statement.isAdmin() && !statement.isKMS() {
+		reasons = append(reasons, fmt.Sprintf("resource '%v' does not match Resource %v", resource, statement.Resources))
+	}
+
+	for _, f := range statement.Conditions.Unsatisfied(args.ConditionValues) {
+		reasons = append(reasons, fmt.Sprintf("condition %v is not satisfied", f))
+	}
+
+	return reasons
+}
+
+// argsResource - returns the resource of given policy args as matched by
+// statement resources.
+func argsResource(args Args) string {
+	resource := args.BucketName
+	if args.ObjectName != "" {
+		if !strings.HasPrefix(args.ObjectName, "/") {
+			resource += "/"
+		}
+
+		resource += args.ObjectName
+	} else {
+		resource += "/"
+	}
+
+	return resource
+}
+
+// toArgsResources - returns resources, e.g. "mybucket" or
+// "mybucket/myobject", in the form IsAllowed matches for args of their bucket
+// and object names, see argsResource.
+func toArgsResources(resources []string) []string {
+	argsResources := make([]string, len(resources))
+	for i, resource := range resources {
+		tokens := strings.SplitN(resource, "/", 2)
+		args := Args{BucketName: tokens[0]}
+		if len(tokens) == 2 {
+			args.ObjectName = tokens[1]
+		}
+		argsResources[i] = argsResource(args)
+	}
+	return argsResources
+}
+
+// FilterAllowedResources - returns the resources, e.g. "mybucket" or
+// "mybucket/myobject", the statement allows for given policy args, in their
+// original order. The result is the same as calling IsAllowed for every
+// resource with its bucket and object names as those of args, but action
+// and conditions are evaluated, and policy variables of statement resources
+// substituted, only once for the batch. Bucket and object names of args are
+// ignored.
+func (statement Statement) FilterAllowedResources(resources []string, args Args) []string {
+	if args.Anonymous {
+		args = anonymousArgs(args)
+	}
+
+	allowed := []string{}
+	for i, matched := range statement.matchResources(statement.substitutedResources(args.ConditionValues), toArgsResources(resources), args) {
+		if statement.Effect.IsAllowed(matched) {
+			allowed = append(allowed, resources[i])
+		}
+	}
+
+	return allowed
+}
+
+// substitutedResources - returns statement resources with policy variables
+// substituted by given condition values.
+func (statement Statement) substitutedResources(conditionValues map[string][]string) []Resource {
+	patterns := make([]Resource, 0, len(statement.Resources))
+	for r := range statement.Resources {
+		patterns = append(patterns, r.substituteVariables(conditionValues))
+	}
+	return patterns
+}
+
+// matchResources - returns for each resource whether the statement,
+// regardless of its effect, matches args with the resource, patterns being
+// the substituted statement resources.
+func (statement Statement) matchResources(patterns []Resource, resources []string, args Args) []bool {
+	matches := make([]bool, len(resources))
+
+	if args.Anonymous && statement.Effect == Allow && statement.usesIdentityKeys() {
+		return matches
+	}
+
+	if (!statement.Actions.Match(args.Action) && !statement.Actions.IsEmpty()) ||
+		statement.NotActions.Match(args.Action) ||
+		!statement.Conditions.Evaluate(args.ConditionValues) {
+		return matches
+	}
+
+	// For admin statements, resource match can be ignored.
+	admin := statement.isAdmin() || statement.isKMS()
+	for i, resource := range resources {
+		matches[i] = admin
+		for _, pattern := range patterns {
+			if matches[i] {
+				break
+			}
+			matches[i] = pattern.MatchResource(resource)
+		}
+	}
+
+	return matches
+}
+
+// ConditionsSatisfied - checks whether the condition block of the statement is
+// satisfied by given condition values, without matching actions or resources.
+func (statement Statement) ConditionsSatisfied(values map[string][]string) bool {
+	return statement.Conditions.Evaluate(values)
+}
+
+// RedundantResources - returns resources of the statement, sorted by their
+// ARN, subsumed by other resources of the statement, e.g. "mybucket/logs/*"
+// in presence of "mybucket/*". Removing them does not change what the
+// statement matches.
+func (statement Statement) RedundantResources() []Resource {
+	return statement.Resources.redundantResources()
+}
+
+// usesIdentityKeys - checks whether conditions or resources of the statement
+// refer to identity keys.
+func (statement Statement) usesIdentityKeys() bool {
+	return usesIdentityKeys(statement.Resources, statement.Conditions)
+}
+
+// usesIdentityKeys - checks whether conditions or resources refer to
+// identity keys, which anonymous requests never have.
+func usesIdentityKeys(resources ResourceSet, conditions condition.Functions) bool {
+	for _, name := range condition.IdentityKeys {
+		for key := range conditions.Keys() {
+			if key.Is(name) {
+				return true
+			}
+		}
+		for resource := range resources {
+			if strings.Contains(resource.Pattern, name.VarName()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (statement Statement) isAdmin() bool {
 	for action := range statement.Actions {
 		if AdminAction(action).IsValid() {
@@ -91,6 +255,14 @@ func (statement Statement) isValid() error {
 		return Errorf("Action must not be empty")
 	}
 
+	// Policy variables are only allowed in Resource and Condition values.
+	if err := statement.Actions.validateNoVariables("Action"); err != nil {
+		return err
+	}
+	if err := statement.NotActions.validateNoVariables("NotAction"); err != nil {
+		return err
+	}
+
 	if statement.isAdmin() {
 		if err := statement.Actions.ValidateAdmin(); err != nil {
 			return err
@@ -19,8 +19,11 @@ package policy
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/trinet2005/oss-pkg/policy/condition"
@@ -519,3 +522,473 @@ func TestStatementValidate(t *testing.T) {
 		}
 	}
 }
+
+func TestStatementPolicyVariables(t *testing.T) {
+	testCases := []struct {
+		statement       Statement
+		expectedElement string
+	}{
+		{NewStatement("",
+			Allow,
+			NewActionSet("s3:${aws:username}"),
+			NewResourceSet(NewResource("mybucket/*")),
+			condition.NewFunctions(),
+		), "Action"},
+		{NewStatementWithNotAction("",
+			Deny,
+			NewActionSet("s3:Get${aws:username}"),
+			NewResourceSet(NewResource("mybucket/*")),
+			condition.NewFunctions(),
+		), "NotAction"},
+		// Policy variables are allowed in Resource.
+		{NewStatement("",
+			Allow,
+			NewActionSet(GetObjectAction),
+			NewResourceSet(NewResource("mybucket/${aws:username}/*")),
+			condition.NewFunctions(),
+		), ""},
+	}
+
+	for i, testCase := range testCases {
+		err := testCase.statement.isValid()
+		if testCase.expectedElement == "" {
+			if err != nil {
+				t.Fatalf("case %v: unexpected error: %v", i+1, err)
+			}
+			continue
+		}
+
+		var verr VariableError
+		if !errors.As(err, &verr) {
+			t.Fatalf("case %v: expected VariableError, got: %v", i+1, err)
+		}
+		if _, ok := err.(Error); !ok {
+			t.Fatalf("case %v: expected policy.Error, got: %T", i+1, err)
+		}
+		if verr.Element != testCase.expectedElement {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedElement, verr.Element)
+		}
+	}
+}
+
+func TestStatementConditionsSatisfied(t *testing.T) {
+	func1, err := condition.NewStringEqualsFunc(
+		"",
+		condition.S3Prefix.ToKey(),
+		"home/",
+		"photos/",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func2, err := condition.NewStringLikeFunc(
+		"",
+		condition.AWSUserAgent.ToKey(),
+		"minio-go/*",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func3, err := condition.NewNumericLessThanFunc(
+		condition.S3MaxKeys.ToKey(),
+		100,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func4, err := condition.NewNullFunc(
+		condition.S3XAmzServerSideEncryption.ToKey(),
+		true,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	// Resources and actions are not considered by ConditionsSatisfied.
+	statement := NewStatement("",
+		Allow,
+		NewActionSet(ListBucketAction),
+		NewResourceSet(NewResource("mybucket")),
+		condition.NewFunctions(func1, func2, func3, func4),
+	)
+
+	testCases := []struct {
+		statement      Statement
+		values         map[string][]string
+		expectedResult bool
+	}{
+		{statement, map[string][]string{
+			"prefix":    {"home/"},
+			"UserAgent": {"minio-go/7.0"},
+			"max-keys":  {"10"},
+		}, true},
+		{statement, map[string][]string{
+			"prefix":    {"photos/"},
+			"UserAgent": {"minio-go/7.0"},
+			"max-keys":  {"99"},
+		}, true},
+		// prefix is not allowed.
+		{statement, map[string][]string{
+			"prefix":    {"tmp/"},
+			"UserAgent": {"minio-go/7.0"},
+			"max-keys":  {"10"},
+		}, false},
+		// user agent does not match.
+		{statement, map[string][]string{
+			"prefix":    {"home/"},
+			"UserAgent": {"aws-cli/2.0"},
+			"max-keys":  {"10"},
+		}, false},
+		// max-keys is too large.
+		{statement, map[string][]string{
+			"prefix":    {"home/"},
+			"UserAgent": {"minio-go/7.0"},
+			"max-keys":  {"1000"},
+		}, false},
+		// encryption header must be absent.
+		{statement, map[string][]string{
+			"prefix":                       {"home/"},
+			"UserAgent":                    {"minio-go/7.0"},
+			"max-keys":                     {"10"},
+			"x-amz-server-side-encryption": {"AES256"},
+		}, false},
+		{statement, map[string][]string{}, false},
+		{NewStatement("", Deny, NewActionSet(GetObjectAction), NewResourceSet(NewResource("*")), condition.NewFunctions()), map[string][]string{}, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.statement.ConditionsSatisfied(testCase.values)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestStatementWhyNotAllowed(t *testing.T) {
+	func1, err := condition.NewStringEqualsFunc(
+		"",
+		condition.S3Prefix.ToKey(),
+		"home/",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	func2, err := condition.NewNullFunc(
+		condition.S3XAmzServerSideEncryption.ToKey(),
+		true,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	statement1 := NewStatement("",
+		Allow,
+		NewActionSet(GetObjectAction, ListBucketAction),
+		NewResourceSet(NewResource("mybucket"), NewResource("mybucket/*")),
+		condition.NewFunctions(func1, func2),
+	)
+
+	statement2 := NewStatementWithNotAction("",
+		Allow,
+		NewActionSet(PutObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(),
+	)
+
+	statement3 := NewStatement("",
+		Deny,
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(),
+	)
+
+	allowedValues := map[string][]string{"prefix": {"home/"}}
+
+	testCases := []struct {
+		statement      Statement
+		args           Args
+		expectedResult []string
+	}{
+		{statement1, Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject", ConditionValues: allowedValues}, nil},
+		{statement1, Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "myobject", ConditionValues: allowedValues}, []string{
+			"action 's3:PutObject' does not match Action [s3:GetObject s3:ListBucket]",
+		}},
+		{statement1, Args{Action: GetObjectAction, BucketName: "otherbucket", ObjectName: "myobject", ConditionValues: allowedValues}, []string{
+			"resource 'otherbucket/myobject' does not match Resource [arn:aws:s3:::mybucket arn:aws:s3:::mybucket/*]",
+		}},
+		{statement1, Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject", ConditionValues: map[string][]string{
+			"prefix":                       {"tmp/"},
+			"x-amz-server-side-encryption": {"AES256"},
+		}}, []string{
+			"condition StringEquals:s3:prefix:[home/] is not satisfied",
+			"condition Null:s3:x-amz-server-side-encryption:true is not satisfied",
+		}},
+		{statement1, Args{Action: PutObjectAction, BucketName: "otherbucket", ObjectName: "myobject"}, []string{
+			"action 's3:PutObject' does not match Action [s3:GetObject s3:ListBucket]",
+			"resource 'otherbucket/myobject' does not match Resource [arn:aws:s3:::mybucket arn:aws:s3:::mybucket/*]",
+			"condition StringEquals:s3:prefix:[home/] is not satisfied",
+		}},
+		{statement2, Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, nil},
+		{statement2, Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, []string{
+			"action 's3:PutObject' matches NotAction [s3:PutObject]",
+		}},
+		{statement3, Args{Action: GetObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, []string{
+			"action 's3:GetObject' on resource 'mybucket/myobject' is explicitly denied",
+		}},
+		{statement3, Args{Action: PutObjectAction, BucketName: "mybucket", ObjectName: "myobject"}, nil},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.statement.WhyNotAllowed(testCase.args)
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestStatementRedundantResources(t *testing.T) {
+	job, err := parseResource("arn:aws:s3:us-east-1:111122223333:job/*")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		resources      ResourceSet
+		expectedResult []Resource
+	}{
+		{NewResourceSet(NewResource("mybucket/*"), NewResource("mybucket/logs/*")), []Resource{NewResource("mybucket/logs/*")}},
+		{NewResourceSet(NewResource("mybucket/*"), NewResource("mybucket/logs/*"), NewResource("mybucket/logs/2021/?.log")), []Resource{
+			NewResource("mybucket/logs/*"),
+			NewResource("mybucket/logs/2021/?.log"),
+		}},
+		{NewResourceSet(NewResource("*"), NewResource("mybucket"), NewResource("mybucket/*")), []Resource{
+			NewResource("mybucket"),
+			NewResource("mybucket/*"),
+		}},
+		{NewResourceSet(NewResource("mybucket*"), NewResource("mybucket"), NewResource("yourbucket")), []Resource{NewResource("mybucket")}},
+		// Equivalent patterns, the first in ARN order is kept.
+		{NewResourceSet(NewResource("mybucket/*"), NewResource("mybucket/**")), []Resource{NewResource("mybucket/**")}},
+		// Non-overlapping resources.
+		{NewResourceSet(NewResource("mybucket"), NewResource("mybucket/*")), []Resource{}},
+		{NewResourceSet(NewResource("mybucket/logs/*"), NewResource("mybucket/photos/*")), []Resource{}},
+		{NewResourceSet(NewResource("mybucket/?"), NewResource("mybucket/a*")), []Resource{}},
+		{NewResourceSet(NewResource("mybucket/a?"), NewResource("mybucket/?b")), []Resource{}},
+		{NewResourceSet(NewResource("*"), job), []Resource{}},
+		{NewResourceSet(), []Resource{}},
+	}
+
+	for i, testCase := range testCases {
+		statement := NewStatement("",
+			Allow,
+			NewActionSet(GetObjectAction),
+			testCase.resources,
+			condition.NewFunctions(),
+		)
+
+		result := statement.RedundantResources()
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+	}
+}
+
+func TestStatementFilterAllowedResources(t *testing.T) {
+	func1, err := condition.NewStringEqualsFunc("", condition.S3XAmzCopySource.ToKey(), "mybucket/myobject")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	statement1 := NewStatement("", Allow, NewActionSet(GetObjectAction, PutObjectAction),
+		NewResourceSet(NewResource("mybucket/photos/*"), NewResource("mybucket/${aws:username}/*")), condition.NewFunctions())
+	statement2 := NewStatement("", Deny, NewActionSet(DeleteObjectAction),
+		NewResourceSet(NewResource("mybucket/locked/*")), condition.NewFunctions())
+	statement3 := NewStatement("", Allow, NewActionSet(PutObjectAction),
+		NewResourceSet(NewResource("mybucket/*")), condition.NewFunctions(func1))
+	statement4 := NewStatementWithNotAction("", Allow, NewActionSet(DeleteObjectAction),
+		NewResourceSet(NewResource("mybucket/*")), condition.NewFunctions())
+	statement5 := NewStatement("", Allow, NewActionSet(ListBucketAction),
+		NewResourceSet(NewResource("mybucket/*")), condition.NewFunctions())
+
+	resources := []string{
+		"mybucket/photos/1.jpg",
+		"mybucket/johndoe/notes.txt",
+		"mybucket/janedoe/notes.txt",
+		"mybucket/locked/a.txt",
+		"yourbucket/photos/1.jpg",
+	}
+
+	testCases := []struct {
+		statement      Statement
+		args           Args
+		resources      []string
+		expectedResult []string
+	}{
+		{statement1, Args{Action: GetObjectAction}, resources, []string{"mybucket/photos/1.jpg"}},
+		{statement1, Args{Action: GetObjectAction, ConditionValues: map[string][]string{"username": {"johndoe"}}}, resources, []string{
+			"mybucket/photos/1.jpg",
+			"mybucket/johndoe/notes.txt",
+		}},
+		{statement1, Args{Action: DeleteObjectAction}, resources, []string{}},
+		{statement2, Args{Action: DeleteObjectAction}, resources, []string{
+			"mybucket/photos/1.jpg",
+			"mybucket/johndoe/notes.txt",
+			"mybucket/janedoe/notes.txt",
+			"yourbucket/photos/1.jpg",
+		}},
+		{statement2, Args{Action: GetObjectAction}, resources, resources},
+		{statement3, Args{Action: PutObjectAction}, resources, []string{}},
+		{statement3, Args{Action: PutObjectAction, ConditionValues: map[string][]string{"x-amz-copy-source": {"mybucket/myobject"}}}, resources, []string{
+			"mybucket/photos/1.jpg",
+			"mybucket/johndoe/notes.txt",
+			"mybucket/janedoe/notes.txt",
+			"mybucket/locked/a.txt",
+		}},
+		{statement4, Args{Action: GetObjectAction}, resources, resources[:4]},
+		{statement4, Args{Action: DeleteObjectAction}, resources, []string{}},
+		// Bare buckets are matched as IsAllowed matches args without object.
+		{statement5, Args{Action: ListBucketAction}, []string{"mybucket", "yourbucket", "mybucket/photos"}, []string{"mybucket", "mybucket/photos"}},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.statement.FilterAllowedResources(testCase.resources, testCase.args)
+
+		if !reflect.DeepEqual(result, testCase.expectedResult) {
+			t.Fatalf("case %v: expected: %v, got: %v\n", i+1, testCase.expectedResult, result)
+		}
+
+		// Same as checking every resource with IsAllowed.
+		for _, resource := range testCase.resources {
+			args := testCase.args
+			tokens := strings.SplitN(resource, "/", 2)
+			args.BucketName = tokens[0]
+			if len(tokens) == 2 {
+				args.ObjectName = tokens[1]
+			}
+			allowed := false
+			for _, r := range result {
+				allowed = allowed || r == resource
+			}
+			if testCase.statement.IsAllowed(args) != allowed {
+				t.Fatalf("case %v: %v: expected: %v, got: %v\n", i+1, resource, testCase.statement.IsAllowed(args), allowed)
+			}
+		}
+	}
+}
+
+func newBenchmarkResources() (Statement, []string) {
+	statement := NewStatement("", Allow, NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/photos/*.jpg"), NewResource("mybucket/${aws:username}/*"), NewResource("mybucket/public/*")), condition.NewFunctions())
+	var resources []string
+	for i := 0; i < 1000; i++ {
+		resources = append(resources, fmt.Sprintf("mybucket/photos/%04d.jpg", i), fmt.Sprintf("mybucket/private/%04d.txt", i))
+	}
+	return statement, resources
+}
+
+func BenchmarkStatementFilterAllowedResources(b *testing.B) {
+	statement, resources := newBenchmarkResources()
+	args := Args{Action: GetObjectAction, ConditionValues: map[string][]string{"username": {"johndoe"}}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		statement.FilterAllowedResources(resources, args)
+	}
+}
+
+func BenchmarkStatementFilterAllowedResourcesIsAllowed(b *testing.B) {
+	statement, resources := newBenchmarkResources()
+	args := Args{Action: GetObjectAction, ConditionValues: map[string][]string{"username": {"johndoe"}}}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, resource := range resources {
+			args.BucketName, args.ObjectName = "mybucket", strings.TrimPrefix(resource, "mybucket/")
+			statement.IsAllowed(args)
+		}
+	}
+}
+
+func TestStatementIsAllowedAnonymous(t *testing.T) {
+	usernameFunc, err := condition.NewStringEqualsFunc("", condition.AWSUsername.ToKey(), "alice")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+	notUsernameFunc, err := condition.NewStringNotEqualsFunc("", condition.AWSUsername.ToKey(), "alice")
+	if err != nil {
+		t.Fatalf("unexpected error. %v\n", err)
+	}
+
+	// Public read of a bucket.
+	statement1 := NewStatement("",
+		Allow,
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(),
+	)
+	// Identity conditioned read.
+	statement2 := NewStatement("",
+		Allow,
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(usernameFunc),
+	)
+	// Per user prefix.
+	statement3 := NewStatement("",
+		Allow,
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/${aws:username}/*")),
+		condition.NewFunctions(),
+	)
+	// Deny everyone except alice.
+	statement4 := NewStatement("",
+		Deny,
+		NewActionSet(GetObjectAction),
+		NewResourceSet(NewResource("mybucket/*")),
+		condition.NewFunctions(notUsernameFunc),
+	)
+
+	anonymousArgs := Args{
+		Action:          GetObjectAction,
+		BucketName:      "mybucket",
+		ObjectName:      "alice/photo.jpg",
+		ConditionValues: map[string][]string{},
+		Anonymous:       true,
+	}
+	// Identity values supplied along with an anonymous request are ignored.
+	spoofedArgs := anonymousArgs
+	spoofedArgs.ConditionValues = map[string][]string{"username": {"alice"}}
+
+	userArgs := anonymousArgs
+	userArgs.Anonymous = false
+	userArgs.ConditionValues = map[string][]string{"username": {"alice"}}
+
+	testCases := []struct {
+		statement      Statement
+		args           Args
+		expectedResult bool
+	}{
+		{statement1, anonymousArgs, true},
+		{statement1, spoofedArgs, true},
+		{statement2, anonymousArgs, false},
+		{statement2, spoofedArgs, false},
+		{statement2, userArgs, true},
+		{statement3, anonymousArgs, false},
+		{statement3, spoofedArgs, false},
+		{statement3, userArgs, true},
+		{statement4, anonymousArgs, false},
+		{statement4, spoofedArgs, false},
+		{statement4, userArgs, true},
+	}
+
+	for i, testCase := range testCases {
+		result := testCase.statement.IsAllowed(testCase.args)
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// terraformStatement - statement as rendered by Terraform's
+// aws_iam_policy_document, field order is significant.
+type terraformStatement struct {
+	Sid        string                            `json:"Sid"`
+	Effect     string                            `json:"Effect,omitempty"`
+	Actions    interface{}                       `json:"Action,omitempty"`
+	NotActions interface{}                       `json:"NotAction,omitempty"`
+	Resources  interface{}                       `json:"Resource,omitempty"`
+	Conditions map[string]map[string]interface{} `json:"Condition,omitempty"`
+}
+
+// terraformPolicy - policy as rendered by Terraform's aws_iam_policy_document.
+type terraformPolicy struct {
+	Version    string               `json:"Version,omitempty"`
+	ID         string               `json:"Id,omitempty"`
+	Statements []terraformStatement `json:"Statement,omitempty"`
+}
+
+// terraformStringList - returns a single value as a string, otherwise
+// values sorted in reverse order as Terraform does.
+func terraformStringList(values []string) interface{} {
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(values)))
+	return values
+}
+
+func terraformConditions(statement Statement) (map[string]map[string]interface{}, error) {
+	if len(statement.Conditions) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(statement.Conditions)
+	if err != nil {
+		return nil, err
+	}
+
+	var nm map[string]map[string][]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err = decoder.Decode(&nm); err != nil {
+		return nil, err
+	}
+
+	conditions := make(map[string]map[string]interface{}, len(nm))
+	for name, args := range nm {
+		conditions[name] = make(map[string]interface{}, len(args))
+		for key, values := range args {
+			// Terraform renders all condition values as strings.
+			svalues := make([]string, 0, len(values))
+			for _, v := range values {
+				svalues = append(svalues, fmt.Sprint(v))
+			}
+			conditions[name][key] = terraformStringList(svalues)
+		}
+	}
+	return conditions, nil
+}
+
+// MarshalTerraform - encodes Policy to JSON data in the form produced by
+// Terraform's aws_iam_policy_document data source. Unlike MarshalJSON,
+// "Sid" is always present, "ID" is written as "Id", single element lists
+// are written as scalars, multi element lists are sorted in reverse order,
+// condition values are written as strings and the output is indented.
+func (iamp Policy) MarshalTerraform() ([]byte, error) {
+	if err := iamp.isValid(); err != nil {
+		return nil, err
+	}
+
+	tp := terraformPolicy{
+		Version: iamp.Version,
+		ID:      string(iamp.ID),
+	}
+	for _, statement := range iamp.Statements {
+		conditions, err := terraformConditions(statement)
+		if err != nil {
+			return nil, err
+		}
+
+		var actions, notActions, resources []string
+		for action := range statement.Actions {
+			actions = append(actions, string(action))
+		}
+		for action := range statement.NotActions {
+			notActions = append(notActions, string(action))
+		}
+		for resource := range statement.Resources {
+			resources = append(resources, resource.String())
+		}
+
+		tp.Statements = append(tp.Statements, terraformStatement{
+			Sid:        string(statement.SID),
+			Effect:     string(statement.Effect),
+			Actions:    terraformStringList(actions),
+			NotActions: terraformStringList(notActions),
+			Resources:  terraformStringList(resources),
+			Conditions: conditions,
+		})
+	}
+
+	return json.MarshalIndent(tp, "", "  ")
+}
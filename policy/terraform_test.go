@@ -0,0 +1,145 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPolicyMarshalTerraform(t *testing.T) {
+	data := `{
+    "Version": "2012-10-17",
+    "Statement": [
+        {
+            "Effect": "Allow",
+            "Action": ["s3:ListBucket"],
+            "Resource": ["arn:aws:s3:::mybucket"],
+            "Condition": {
+                "StringLike": {
+                    "s3:prefix": ["home/", "home/*"]
+                }
+            }
+        },
+        {
+            "Sid": "ReadWrite",
+            "Effect": "Allow",
+            "Action": ["s3:GetObject", "s3:PutObject"],
+            "Resource": "arn:aws:s3:::mybucket/home/*"
+        },
+        {
+            "Sid": "DenyInsecure",
+            "Effect": "Deny",
+            "NotAction": "s3:GetBucketLocation",
+            "Resource": ["arn:aws:s3:::mybucket/*", "arn:aws:s3:::mybucket"],
+            "Condition": {
+                "Bool": {
+                    "aws:SecureTransport": false
+                }
+            }
+        }
+    ]
+}`
+
+	// As generated by Terraform's aws_iam_policy_document data source.
+	expected := `{
+  "Version": "2012-10-17",
+  "Statement": [
+    {
+      "Sid": "",
+      "Effect": "Allow",
+      "Action": "s3:ListBucket",
+      "Resource": "arn:aws:s3:::mybucket",
+      "Condition": {
+        "StringLike": {
+          "s3:prefix": [
+            "home/*",
+            "home/"
+          ]
+        }
+      }
+    },
+    {
+      "Sid": "ReadWrite",
+      "Effect": "Allow",
+      "Action": [
+        "s3:PutObject",
+        "s3:GetObject"
+      ],
+      "Resource": "arn:aws:s3:::mybucket/home/*"
+    },
+    {
+      "Sid": "DenyInsecure",
+      "Effect": "Deny",
+      "NotAction": "s3:GetBucketLocation",
+      "Resource": [
+        "arn:aws:s3:::mybucket/*",
+        "arn:aws:s3:::mybucket"
+      ],
+      "Condition": {
+        "Bool": {
+          "aws:SecureTransport": "false"
+        }
+      }
+    }
+  ]
+}`
+
+	p, err := ParseConfig(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := p.MarshalTerraform()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(result) != expected {
+		t.Fatalf("expected: %s, got: %s", expected, result)
+	}
+
+	// Terraform output must parse back to the same policy and marshal
+	// identically.
+	rp, err := ParseConfig(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rp.Equals(*p) {
+		t.Fatalf("expected: %v, got: %v", p, rp)
+	}
+	rresult, err := rp.MarshalTerraform()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(rresult, result) {
+		t.Fatalf("expected: %s, got: %s", result, rresult)
+	}
+}
+
+func TestPolicyMarshalTerraformInvalid(t *testing.T) {
+	p := Policy{
+		Version: "1.0",
+		Statements: []Statement{
+			NewStatement("", Allow, NewActionSet(GetObjectAction), NewResourceSet(NewResource("mybucket/*")), nil),
+		},
+	}
+	if _, err := p.MarshalTerraform(); err == nil {
+		t.Fatalf("expected error")
+	}
+}
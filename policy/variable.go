@@ -0,0 +1,56 @@
+// Copyright (c) 2015-2023 MinIO, Inc.
+//
+// This file is part of MinIO Object Storage stack
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package policy
+
+import (
+	"strings"
+)
+
+// hasPolicyVariable - returns whether s contains a policy variable.
+func hasPolicyVariable(s string) bool {
+	i := strings.Index(s, "${")
+	return i >= 0 && strings.Contains(s[i:], "}")
+}
+
+// policyVariables - returns unique policy variables in s, such as
+// "${aws:username}", in the order of their first occurrence.
+func policyVariables(s string) []string {
+	var vars []string
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			return vars
+		}
+		j := strings.Index(s[i:], "}")
+		if j < 0 {
+			return vars
+		}
+		v := s[i : i+j+1]
+		found := false
+		for _, u := range vars {
+			if u == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			vars = append(vars, v)
+		}
+		s = s[i+j+1:]
+	}
+}
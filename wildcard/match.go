@@ -17,6 +17,11 @@
 
 package wildcard
 
+import (
+	"errors"
+	"strings"
+)
+
 // MatchSimple - finds whether the text matches/satisfies the pattern string.
 // supports '*' wildcard in the pattern and ? for single characters.
 // Only difference to Match is that `?` at the end is optional,
@@ -29,13 +34,16 @@ func MatchSimple(pattern, name string) bool {
 		return true
 	}
 	// Do an extended wildcard '*' and '?' match.
-	return deepMatchRune([]rune(name), []rune(pattern), true)
+	return deepMatchRune([]rune(name), []rune(pattern), true, '?', '*')
 }
 
 // Match -  finds whether the text matches/satisfies the pattern string.
 // supports  '*' and '?' wildcards in the pattern string.
 // unlike path.Match(), considers a path as a flat name space while matching the pattern.
 // The difference is illustrated in the example here https://play.golang.org/p/Ega9qgD4Qz .
+// A literal suffix after '*' is anchored at the end of the text and '*' may
+// match an empty string, e.g. `logs/*.log` matches `logs/.log` and
+// `logs/2023/a.log` but not `logs/a.log.txt`.
 func Match(pattern, name string) (matched bool) {
 	if pattern == "" {
 		return name == pattern
@@ -44,23 +52,131 @@ func Match(pattern, name string) (matched bool) {
 		return true
 	}
 	// Do an extended wildcard '*' and '?' match.
-	return deepMatchRune([]rune(name), []rune(pattern), false)
+	return deepMatchRune([]rune(name), []rune(pattern), false, '?', '*')
+}
+
+// MatchWithMetachars - finds whether the text matches the pattern like
+// Match, with single as the wildcard matching a single character instead of
+// '?' and multi as the wildcard matching any characters instead of '*',
+// e.g. '_' and '%' for SQL LIKE style patterns. single and multi must
+// differ. There is no escaping, so '?' and '*' match literally when other
+// metacharacters are used, while single and multi can not be matched
+// literally.
+func MatchWithMetachars(pattern, name string, single, multi rune) bool {
+	if pattern == "" {
+		return name == pattern
+	}
+	if pattern == string(multi) {
+		return true
+	}
+	return deepMatchRune([]rune(name), []rune(pattern), false, single, multi)
+}
+
+// MatchWithSlashes - matches like MatchWithMetachars, except that single
+// matches '/' only if singleSlash is true and multi matches '/' only if
+// multiSlash is true, e.g. with multiSlash false "a/*" matches "a/b" but
+// not "a/b/c", like '*' of shell globs.
+func MatchWithSlashes(pattern, name string, single, multi rune, singleSlash, multiSlash bool) bool {
+	if pattern == "" {
+		return name == pattern
+	}
+	if pattern == string(multi) && multiSlash {
+		return true
+	}
+	return deepMatchSlashes([]rune(name), []rune(pattern), single, multi, singleSlash, multiSlash)
 }
 
-func deepMatchRune(str, pattern []rune, simple bool) bool {
+func deepMatchSlashes(str, pattern []rune, single, multi rune, singleSlash, multiSlash bool) bool {
 	for len(pattern) > 0 {
 		switch pattern[0] {
 		default:
 			if len(str) == 0 || str[0] != pattern[0] {
 				return false
 			}
+		case single:
+			if len(str) == 0 || (!singleSlash && str[0] == '/') {
+				return false
+			}
+		case multi:
+			if deepMatchSlashes(str, pattern[1:], single, multi, singleSlash, multiSlash) {
+				return true
+			}
+			if len(str) == 0 || (!multiSlash && str[0] == '/') {
+				return false
+			}
+			return deepMatchSlashes(str[1:], pattern, single, multi, singleSlash, multiSlash)
+		}
+		str = str[1:]
+		pattern = pattern[1:]
+	}
+	return len(str) == 0 && len(pattern) == 0
+}
+
+// ErrMatchLimitExceeded - returned by MatchWithLimit when matching takes
+// more steps than allowed.
+var ErrMatchLimitExceeded = errors.New("wildcard: match step limit exceeded")
+
+// MatchWithLimit - matches like Match, but gives up with
+// ErrMatchLimitExceeded once more than limit matching steps are taken.
+// Each '*' of the pattern is tried against every remaining suffix of name,
+// so patterns like "*a*a*a*b" backtrack exponentially on names like
+// "aaaaaaaa". A step is one attempt of matching a pattern suffix at a name
+// offset.
+func MatchWithLimit(pattern, name string, limit int) (bool, error) {
+	if pattern == "" {
+		return name == pattern, nil
+	}
+	if pattern == "*" {
+		return true, nil
+	}
+	return deepMatchRuneLimit([]rune(name), []rune(pattern), &limit)
+}
+
+func deepMatchRuneLimit(str, pattern []rune, steps *int) (bool, error) {
+	if *steps <= 0 {
+		return false, ErrMatchLimitExceeded
+	}
+	*steps--
+
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		default:
+			if len(str) == 0 || str[0] != pattern[0] {
+				return false, nil
+			}
 		case '?':
 			if len(str) == 0 {
-				return simple
+				return false, nil
 			}
 		case '*':
-			return deepMatchRune(str, pattern[1:], simple) ||
-				(len(str) > 0 && deepMatchRune(str[1:], pattern, simple))
+			if matched, err := deepMatchRuneLimit(str, pattern[1:], steps); matched || err != nil {
+				return matched, err
+			}
+			if len(str) == 0 {
+				return false, nil
+			}
+			return deepMatchRuneLimit(str[1:], pattern, steps)
+		}
+		str = str[1:]
+		pattern = pattern[1:]
+	}
+	return len(str) == 0 && len(pattern) == 0, nil
+}
+
+func deepMatchRune(str, pattern []rune, simple bool, single, multi rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		default:
+			if len(str) == 0 || str[0] != pattern[0] {
+				return false
+			}
+		case single:
+			if len(str) == 0 {
+				return simple
+			}
+		case multi:
+			return deepMatchRune(str, pattern[1:], simple, single, multi) ||
+				(len(str) > 0 && deepMatchRune(str[1:], pattern, simple, single, multi))
 		}
 		str = str[1:]
 		pattern = pattern[1:]
@@ -68,6 +184,52 @@ func deepMatchRune(str, pattern []rune, simple bool) bool {
 	return len(str) == 0 && len(pattern) == 0
 }
 
+// MatchCapture - finds whether name matches the pattern like Match and
+// returns the substrings consumed by each '*' and '?' wildcard, left to
+// right. When several expansions match, each '*' consumes as little as
+// possible from left to right, i.e. `a*b*c` against `abbc` captures
+// ["", "b"]. Captures are nil when name does not match.
+func MatchCapture(pattern, name string) (captures []string, matched bool) {
+	if pattern == "" {
+		if name != "" {
+			return nil, false
+		}
+		return []string{}, true
+	}
+	return deepMatchCapture([]rune(name), []rune(pattern), []string{})
+}
+
+func deepMatchCapture(str, pattern []rune, captures []string) ([]string, bool) {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		default:
+			if len(str) == 0 || str[0] != pattern[0] {
+				return nil, false
+			}
+		case '?':
+			if len(str) == 0 {
+				return nil, false
+			}
+			captures = append(captures, string(str[:1]))
+		case '*':
+			for i := 0; i <= len(str); i++ {
+				// Copy so that backtracking never shares captures.
+				c := append(append([]string{}, captures...), string(str[:i]))
+				if result, ok := deepMatchCapture(str[i:], pattern[1:], c); ok {
+					return result, true
+				}
+			}
+			return nil, false
+		}
+		str = str[1:]
+		pattern = pattern[1:]
+	}
+	if len(str) != 0 {
+		return nil, false
+	}
+	return captures, true
+}
+
 // MatchAsPatternPrefix matches text as a prefix of the given pattern. Examples:
 //
 //	| Pattern | Text    | Match Result |
@@ -81,6 +243,11 @@ func deepMatchRune(str, pattern []rune, simple bool) bool {
 //	| ab??d   | abc     | True         |
 //	| ab??d   | abcxdd  | False        |
 //
+// The text is matched from the start of the pattern only, e.g. for pattern
+// `bucket/bucket/x` the texts `bucket/` and `bucket/bucket/` match while
+// `bucketbucket/` does not, and text `bucket/bucket/` does not match pattern
+// `bucket/x`. Literal runs repeating earlier in the pattern have no effect.
+//
 // This function is only useful in some special situations.
 func MatchAsPatternPrefix(pattern, text string) bool {
 	return matchAsPatternPrefix([]rune(pattern), []rune(text))
@@ -100,3 +267,66 @@ func matchAsPatternPrefix(pattern, text []rune) bool {
 	}
 	return len(text) <= len(pattern)
 }
+
+// Simplify - returns a pattern matching exactly the same names as pattern
+// with Match, with redundant wildcards removed. Every run of '*' and '?'
+// containing at least one '*' matches any text of at least as many
+// characters as it has '?', hence it is rewritten as its '?' followed by a
+// single '*', e.g. `a**b` becomes `a*b` and `a*?*b` becomes `a?*b`. Leading
+// and trailing '*' are significant, e.g. `*bucket*` is not `bucket`, and are
+// kept. The result is not equivalent for MatchSimple.
+func Simplify(pattern string) string {
+	var b strings.Builder
+	for i := 0; i < len(pattern); {
+		if pattern[i] != '*' && pattern[i] != '?' {
+			b.WriteByte(pattern[i])
+			i++
+			continue
+		}
+
+		stars, questions := 0, 0
+		for ; i < len(pattern) && (pattern[i] == '*' || pattern[i] == '?'); i++ {
+			if pattern[i] == '*' {
+				stars++
+			} else {
+				questions++
+			}
+		}
+
+		b.WriteString(strings.Repeat("?", questions))
+		if stars > 0 {
+			b.WriteByte('*')
+		}
+	}
+	return b.String()
+}
+
+// MatchPattern - finds whether every name matched by subPattern with Match
+// is also matched by pattern. Wildcards of subPattern are only matched by
+// wildcards of pattern, '*' by '*' and '?' by '?' or '*', e.g. `a/*`
+// matches `a/b*` and `a/?` but `a/?` does not match `a/*`. Some equivalent
+// patterns, like `*?` and `?*`, are not matched unless simplified first.
+func MatchPattern(pattern, subPattern string) bool {
+	return deepMatchPattern([]rune(Simplify(pattern)), []rune(Simplify(subPattern)))
+}
+
+func deepMatchPattern(pattern, sub []rune) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		default:
+			if len(sub) == 0 || sub[0] != pattern[0] {
+				return false
+			}
+		case '?':
+			if len(sub) == 0 || sub[0] == '*' {
+				return false
+			}
+		case '*':
+			return deepMatchPattern(pattern[1:], sub) ||
+				(len(sub) > 0 && deepMatchPattern(pattern, sub[1:]))
+		}
+		sub = sub[1:]
+		pattern = pattern[1:]
+	}
+	return len(sub) == 0
+}
@@ -18,9 +18,25 @@
 package wildcard
 
 import (
+	"math/rand"
+	"reflect"
+	"strings"
 	"testing"
 )
 
+// newRandomString - returns a generator of random strings of up to maxLen
+// characters of alphabet, deterministic for the seed.
+func newRandomString(seed int64) func(alphabet string, maxLen int) string {
+	r := rand.New(rand.NewSource(seed))
+	return func(alphabet string, maxLen int) string {
+		b := make([]byte, r.Intn(maxLen+1))
+		for i := range b {
+			b[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return string(b)
+	}
+}
+
 // TestMatch - Tests validate the logic of wild card matching.
 // `Match` supports '*' and '?' wildcards.
 // Sample usage: In resource matching for bucket policy validation.
@@ -373,6 +389,54 @@ func TestMatch(t *testing.T) {
 			text:    "a",
 			matched: false,
 		},
+		// Test case - 54.
+		// Test case with "*" followed by a literal suffix.
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/a.log",
+			matched: true,
+		},
+		// Test case - 55.
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/a.txt",
+			matched: false,
+		},
+		// Test case - 56.
+		// Test case with "*" matching an empty string before the suffix.
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/.log",
+			matched: true,
+		},
+		// Test case - 57.
+		// Test case with the suffix occurring before the end of text.
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/a.log.txt",
+			matched: false,
+		},
+		// Test case - 58.
+		// Test case with the suffix occurring more than once.
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/a.log.log",
+			matched: true,
+		},
+		// Test case - 59.
+		// Test case with "*" matching across "/".
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/2023/a.log",
+			matched: true,
+		},
+		// Test case - 60.
+		// Test case with text shorter than the suffix.
+		{
+			pattern: "bucket/logs/*.log",
+			text:    "bucket/logs/log",
+			matched: false,
+		},
 	}
 	// Iterating over the test cases, call the function under test and assert the output.
 	for i, testCase := range testCases {
@@ -638,6 +702,43 @@ func TestMatchAsPatternPrefix(t *testing.T) {
 			text:    "abcxdd",
 			matched: false,
 		},
+		// Object paths repeating the bucket name, the text is matched
+		// from the start of the pattern only.
+		{
+			pattern: "bucket/bucket/x",
+			text:    "bucket/",
+			matched: true,
+		},
+		{ // case 18
+			pattern: "bucket/bucket/x",
+			text:    "bucket/bucket/",
+			matched: true,
+		},
+		{
+			pattern: "bucket/bucket/x",
+			text:    "bucket/bucket/x/",
+			matched: false,
+		},
+		{
+			pattern: "bucket/bucket/x",
+			text:    "bucketbucket/",
+			matched: false,
+		},
+		{ // case 21
+			pattern: "bucket/x",
+			text:    "bucket/bucket/",
+			matched: false,
+		},
+		{
+			pattern: "bucket*/bucket/x",
+			text:    "bucketbucket/",
+			matched: true,
+		},
+		{
+			pattern: "bucket?bucket/x",
+			text:    "bucket/",
+			matched: true,
+		},
 	}
 	for i, testCase := range testCases {
 		actualResult := MatchAsPatternPrefix(testCase.pattern, testCase.text)
@@ -646,3 +747,331 @@ func TestMatchAsPatternPrefix(t *testing.T) {
 		}
 	}
 }
+
+func TestMatchCapture(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		text     string
+		captures []string
+		matched  bool
+	}{
+		{
+			pattern:  "a*b*c",
+			text:     "axbyc",
+			captures: []string{"x", "y"},
+			matched:  true,
+		},
+		// Each '*' consumes as little as possible from left to right.
+		{
+			pattern:  "a*b*c",
+			text:     "abbc",
+			captures: []string{"", "b"},
+			matched:  true,
+		},
+		{
+			pattern:  "a*b*c",
+			text:     "axbybzc",
+			captures: []string{"x", "ybz"},
+			matched:  true,
+		},
+		{
+			pattern:  "*",
+			text:     "my-bucket/India/Karnataka/",
+			captures: []string{"my-bucket/India/Karnataka/"},
+			matched:  true,
+		},
+		{
+			pattern:  "*",
+			text:     "",
+			captures: []string{""},
+			matched:  true,
+		},
+		{
+			pattern:  "my-bucket/?0/*.jpg",
+			text:     "my-bucket/20/2010/photo.jpg",
+			captures: []string{"2", "2010/photo"},
+			matched:  true,
+		},
+		{
+			pattern:  "my-bucket/oo",
+			text:     "my-bucket/oo",
+			captures: []string{},
+			matched:  true,
+		},
+		{
+			pattern:  "a*b*c",
+			text:     "axbyd",
+			captures: nil,
+			matched:  false,
+		},
+		{
+			pattern:  "ab?",
+			text:     "ab",
+			captures: nil,
+			matched:  false,
+		},
+		{
+			pattern:  "",
+			text:     "",
+			captures: []string{},
+			matched:  true,
+		},
+	}
+	for i, testCase := range testCases {
+		captures, matched := MatchCapture(testCase.pattern, testCase.text)
+		if testCase.matched != matched {
+			t.Errorf("Test %d: Expected the result to be `%v`, but instead found it to be `%v`", i+1, testCase.matched, matched)
+		}
+		if matched != Match(testCase.pattern, testCase.text) {
+			t.Errorf("Test %d: Expected the result to be same as Match", i+1)
+		}
+		if !reflect.DeepEqual(testCase.captures, captures) {
+			t.Errorf("Test %d: Expected the captures to be `%q`, but instead found it to be `%q`", i+1, testCase.captures, captures)
+		}
+	}
+}
+
+func TestSimplify(t *testing.T) {
+	testCases := []struct {
+		pattern        string
+		expectedResult string
+	}{
+		{"", ""},
+		{"*", "*"},
+		{"**", "*"},
+		{"?", "?"},
+		{"??", "??"},
+		{"*?", "?*"},
+		{"*?*", "?*"},
+		{"?*?", "??*"},
+		{"bucket", "bucket"},
+		{"bucket/*", "bucket/*"},
+		{"bucket/**", "bucket/*"},
+		{"bucket/***/x", "bucket/*/x"},
+		{"bucket/*/", "bucket/*/"},
+		{"*bucket*", "*bucket*"},
+		{"**bucket**", "*bucket*"},
+		{"bucket/*?*?*.jpg", "bucket/??*.jpg"},
+		{"bucket/?/*/?", "bucket/?/*/?"},
+		{"bucket/${aws:username}/**", "bucket/${aws:username}/*"},
+		{"bücket/**", "bücket/*"},
+	}
+
+	for i, testCase := range testCases {
+		result := Simplify(testCase.pattern)
+
+		if result != testCase.expectedResult {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedResult, result)
+		}
+
+		if Simplify(result) != result {
+			t.Fatalf("case %v: expected Simplify to be idempotent for %v", i+1, result)
+		}
+	}
+}
+
+// TestSimplifyEquivalence - checks Match gives the same result for random
+// patterns and their simplified form over random names.
+func TestSimplifyEquivalence(t *testing.T) {
+	randomString := newRandomString(1)
+
+	for i := 0; i < 2000; i++ {
+		pattern := randomString("ab/*?", 8)
+		simplified := Simplify(pattern)
+		for j := 0; j < 50; j++ {
+			name := randomString("ab/", 8)
+			if Match(pattern, name) != Match(simplified, name) {
+				t.Fatalf("pattern %v simplified to %v: expected: %v, got: %v for %v",
+					pattern, simplified, Match(pattern, name), Match(simplified, name), name)
+			}
+		}
+	}
+}
+
+func TestMatchPattern(t *testing.T) {
+	testCases := []struct {
+		pattern    string
+		subPattern string
+		matched    bool
+	}{
+		{"", "", true},
+		{"*", "", true},
+		{"*", "*", true},
+		{"*", "a/b?*", true},
+		{"a/*", "a/b*", true},
+		{"a/*", "a/?", true},
+		{"a/?", "a/*", false},
+		{"a/?", "a/?", true},
+		{"a/?", "a/b", true},
+		{"a/b", "a/?", false},
+		{"a/b*", "a/*", false},
+		{"a/**", "a/*", true},
+		{"a/*", "a/**", true},
+		{"?*", "*?", true},
+		{"*.log", "logs/*.log", true},
+		{"*.log", "logs/*", false},
+		{"s3:Get*", "s3:GetObject", true},
+		{"s3:Get*", "s3:*Object", false},
+		{"s3:*Object", "s3:Get*", false},
+		{"a", "", false},
+		{"", "a", false},
+	}
+
+	for i, testCase := range testCases {
+		result := MatchPattern(testCase.pattern, testCase.subPattern)
+		if result != testCase.matched {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.matched, result)
+		}
+	}
+}
+
+// TestMatchPatternSound - checks names matched by random sub patterns are
+// matched by random patterns reported to contain them.
+func TestMatchPatternSound(t *testing.T) {
+	randomString := newRandomString(1)
+
+	matched := 0
+	for i := 0; i < 20000; i++ {
+		pattern, subPattern := randomString("ab*?", 5), randomString("ab*?", 5)
+		if !MatchPattern(pattern, subPattern) {
+			continue
+		}
+		matched++
+		for j := 0; j < 50; j++ {
+			name := randomString("ab", 8)
+			if Match(subPattern, name) && !Match(pattern, name) {
+				t.Fatalf("pattern %v reported to contain %v but does not match %v", pattern, subPattern, name)
+			}
+		}
+	}
+	if matched == 0 {
+		t.Fatalf("expected some random patterns to contain others")
+	}
+}
+
+func TestMatchWithMetachars(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		text    string
+		matched bool
+	}{
+		{"", "", true},
+		{"", "a", false},
+		{"%", "", true},
+		{"%", "any/thing", true},
+		{"a%", "abc", true},
+		{"a%c", "abbbc", true},
+		{"a%c", "abbbd", false},
+		{"a_c", "abc", true},
+		{"a_c", "ac", false},
+		{"a__", "abc", true},
+		{"%.log", "a/b.log", true},
+		{"_%", "", false},
+		{"*", "abc", false},
+		{"*", "*", true},
+		{"a?c", "abc", false},
+		{"a?c", "a?c", true},
+	}
+
+	for i, testCase := range testCases {
+		result := MatchWithMetachars(testCase.pattern, testCase.text, '_', '%')
+		if result != testCase.matched {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.matched, result)
+		}
+
+		// Patterns translated to the default wildcards match the same.
+		translated := strings.NewReplacer("*", "", "?", "", "_", "?", "%", "*").Replace(testCase.pattern)
+		if !strings.ContainsAny(testCase.pattern, "*?") && Match(translated, testCase.text) != result {
+			t.Fatalf("case %v: expected Match(%v) to agree", i+1, translated)
+		}
+	}
+}
+
+func TestMatchWithLimit(t *testing.T) {
+	testCases := []struct {
+		pattern     string
+		text        string
+		limit       int
+		matched     bool
+		expectedErr error
+	}{
+		{"", "", 0, true, nil},
+		{"*", "abc", 0, true, nil},
+		{"abc", "abc", 1, true, nil},
+		{"abc", "abc", 0, false, ErrMatchLimitExceeded},
+		{"a*c", "abbbc", 10, true, nil},
+		{"a*c", "abbbd", 10, false, nil},
+		{"a?c", "abc", 1, true, nil},
+		{"a*c", "abbbc", 2, false, ErrMatchLimitExceeded},
+		{strings.Repeat("*a", 20) + "b", strings.Repeat("a", 40), 100000, false, ErrMatchLimitExceeded},
+		{strings.Repeat("*a", 3) + "b", strings.Repeat("a", 40) + "b", 100000, true, nil},
+	}
+
+	for i, testCase := range testCases {
+		matched, err := MatchWithLimit(testCase.pattern, testCase.text, testCase.limit)
+		if err != testCase.expectedErr {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.expectedErr, err)
+		}
+		if matched != testCase.matched {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.matched, matched)
+		}
+	}
+
+	// Without reaching the limit the result is the same as Match.
+	randomString := newRandomString(1)
+	for i := 0; i < 20000; i++ {
+		pattern, name := randomString("ab*?", 6), randomString("ab", 8)
+		matched, err := MatchWithLimit(pattern, name, 100000)
+		if err != nil {
+			t.Fatalf("%v %v: unexpected error: %v", pattern, name, err)
+		}
+		if matched != Match(pattern, name) {
+			t.Fatalf("%v %v: expected: %v, got: %v", pattern, name, Match(pattern, name), matched)
+		}
+	}
+}
+
+func TestMatchWithSlashes(t *testing.T) {
+	testCases := []struct {
+		pattern     string
+		text        string
+		singleSlash bool
+		multiSlash  bool
+		matched     bool
+	}{
+		{"", "", false, false, true},
+		{"*", "a/b", true, true, true},
+		{"*", "a/b", true, false, false},
+		{"*", "ab", true, false, true},
+		{"a/*", "a/b", false, false, true},
+		{"a/*", "a/b/c", false, false, false},
+		{"a/*/c", "a/b/c", false, false, true},
+		{"a/*/c", "a//c", false, false, true},
+		{"a/*", "a/", false, false, true},
+		{"a?b", "a/b", false, true, false},
+		{"a?b", "a/b", true, false, true},
+		{"a?b", "axb", false, false, true},
+		{"a/%", "a/b/c", false, false, false},
+	}
+
+	for i, testCase := range testCases {
+		result := MatchWithSlashes(testCase.pattern, testCase.text, '?', '*', testCase.singleSlash, testCase.multiSlash)
+		if result != testCase.matched {
+			t.Fatalf("case %v: expected: %v, got: %v", i+1, testCase.matched, result)
+		}
+	}
+
+	// Custom metacharacters compose.
+	if !MatchWithSlashes("a/%/_", "a/b/c", '_', '%', false, false) || MatchWithSlashes("a/%", "a/b/c", '_', '%', false, false) {
+		t.Fatalf("expected custom metacharacters to respect slashes")
+	}
+
+	// Matching '/' everywhere is the same as Match.
+	randomString := newRandomString(1)
+	for i := 0; i < 20000; i++ {
+		pattern, name := randomString("a/*?", 6), randomString("a/", 8)
+		if MatchWithSlashes(pattern, name, '?', '*', true, true) != Match(pattern, name) {
+			t.Fatalf("%v %v: expected: %v", pattern, name, Match(pattern, name))
+		}
+	}
+}